@@ -0,0 +1,67 @@
+package treap
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Atomic is a thread-safe reference to a treap.  Reads are wait-free, and writes are
+// performed using a CAS loop.
+//
+// The zero value is NOT ready to use; call NewAtomic.
+type Atomic struct {
+	h   Handle
+	ptr unsafe.Pointer // *Node
+
+	mu       sync.RWMutex // guards watchers; held exclusively while publishing
+	watchers []*Watcher
+}
+
+// NewAtomic returns an Atomic reference to an empty treap.
+func NewAtomic(h Handle) *Atomic {
+	return &Atomic{h: h}
+}
+
+// Handle returns the handle used to operate on the treap.
+func (a *Atomic) Handle() Handle {
+	return a.h
+}
+
+// Load the current root.
+func (a *Atomic) Load() *Node {
+	return (*Node)(atomic.LoadPointer(&a.ptr))
+}
+
+// CompareAndSwap replaces the root with new if the current root is old.  It reports
+// whether the swap took place.
+func (a *Atomic) CompareAndSwap(old, new *Node) bool {
+	a.mu.RLock()
+	if len(a.watchers) == 0 {
+		defer a.mu.RUnlock()
+		return atomic.CompareAndSwapPointer(&a.ptr, unsafe.Pointer(old), unsafe.Pointer(new))
+	}
+	a.mu.RUnlock()
+
+	// Slow path.  Commits are serialized so that watchers observe them in order.
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !atomic.CompareAndSwapPointer(&a.ptr, unsafe.Pointer(old), unsafe.Pointer(new)) {
+		return false
+	}
+
+	a.publish(Version{Old: old, New: new})
+	return true
+}
+
+// Update replaces the root with f(root), retrying on conflict.  As such, f SHOULD NOT
+// have side-effects.  Update returns the new root.
+func (a *Atomic) Update(f func(*Node) *Node) *Node {
+	for {
+		old := a.Load()
+		if new := f(old); new == old || a.CompareAndSwap(old, new) {
+			return new
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package treap_test
+
+import (
+	"testing"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomic(t *testing.T) {
+	t.Parallel()
+
+	a := treap.NewAtomic(handle)
+	assert.Nil(t, a.Load())
+
+	root := a.Update(func(n *treap.Node) *treap.Node {
+		n, _ = handle.Insert(n, 1, "a", 1)
+		return n
+	})
+	assert.Equal(t, root, a.Load())
+
+	next, _ := handle.Insert(root, 2, "b", 2)
+	assert.False(t, a.CompareAndSwap(nil, next), "CAS should fail on stale root")
+	assert.True(t, a.CompareAndSwap(root, next))
+	assert.Equal(t, next, a.Load())
+}
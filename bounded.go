@@ -0,0 +1,65 @@
+package treap
+
+// Bounded is a persistent treap that holds at most a fixed number of items.  Inserting
+// into a full Bounded evicts the item with the lowest weight, so that it retains the K
+// heaviest items it has seen.  Use MaxTreap to retain the K lightest items instead.
+//
+// Bounded is an immutable value; its methods return a new Bounded and leave the
+// receiver unchanged.  The zero value is NOT ready to use; see NewBounded.
+type Bounded struct {
+	h    Handle
+	cap  int
+	root *Node
+	len  int
+}
+
+// NewBounded returns an empty Bounded that holds at most capacity items.
+func NewBounded(h Handle, capacity int) Bounded {
+	if capacity <= 0 {
+		panic("treap: bounded capacity must be positive")
+	}
+
+	return Bounded{h: h, cap: capacity}
+}
+
+// Root of the underlying treap, which holds the item with the lowest weight.  The
+// treap can be read using the Handle that was passed to NewBounded.
+func (b Bounded) Root() *Node { return b.root }
+
+// Len returns the number of items.
+func (b Bounded) Len() int { return b.len }
+
+// Cap returns the maximum number of items.
+func (b Bounded) Cap() int { return b.cap }
+
+// Insert an item.  If the Bounded is full, the item with the lowest weight is evicted,
+// and returned.  This may be the inserted item itself, if its weight is not greater
+// than that of every item already present.  The evicted node's Left and Right fields
+// SHOULD be ignored.
+//
+// If the key is already present, Insert is a nop, and returns the receiver and a nil
+// node.
+//
+// O(log n)
+func (b Bounded) Insert(key, val, weight interface{}) (Bounded, *Node) {
+	if b.len < b.cap {
+		if root, ok := b.h.Insert(b.root, key, val, weight); ok {
+			b.root = root
+			b.len++
+		}
+
+		return b, nil
+	}
+
+	if _, ok := b.h.GetNode(b.root, key); ok {
+		return b, nil
+	}
+
+	if b.h.CompareWeights(weight, b.root.Weight) <= 0 {
+		return b, &Node{Key: key, Value: val, Weight: weight}
+	}
+
+	evicted := b.root
+	b.root, _ = b.h.Insert(b.h.Merge(evicted.Left, evicted.Right), key, val, weight)
+	return b, evicted
+}
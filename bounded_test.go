@@ -0,0 +1,95 @@
+package treap_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBounded(t *testing.T) {
+	t.Parallel()
+
+	const k = 10
+
+	t.Run("TopK", func(t *testing.T) {
+		var (
+			b       = treap.NewBounded(handle, k)
+			cs      = mkTestCases(1000)
+			evicted int
+		)
+
+		for _, tc := range cs {
+			var n *treap.Node
+			if b, n = b.Insert(tc.key, tc.value, tc.weight); n != nil {
+				evicted++
+			}
+		}
+
+		assert.Equal(t, k, b.Len())
+		assert.Equal(t, k, b.Cap())
+		assert.Equal(t, len(cs)-k, evicted)
+
+		sort.Slice(cs, func(i, j int) bool { return cs[i].weight > cs[j].weight })
+
+		var got []int
+		for it := handle.Iter(b.Root()); it.Node != nil; it.Next() {
+			got = append(got, it.Weight.(int))
+		}
+
+		var want []int
+		for _, tc := range cs[:k] {
+			want = append(want, tc.weight)
+		}
+
+		assert.ElementsMatch(t, want, got)
+	})
+
+	t.Run("Evict", func(t *testing.T) {
+		b := treap.NewBounded(handle, 2)
+
+		b, n := b.Insert(1, "a", 10)
+		require.Nil(t, n)
+		b, n = b.Insert(2, "b", 20)
+		require.Nil(t, n)
+
+		b2, n := b.Insert(3, "c", 5)
+		require.NotNil(t, n)
+		assert.Equal(t, 3, n.Key, "light item should be evicted directly")
+		assert.Equal(t, b.Root(), b2.Root())
+
+		b2, n = b.Insert(3, "c", 30)
+		require.NotNil(t, n)
+		assert.Equal(t, 1, n.Key, "root should be evicted")
+		assert.Equal(t, 2, b2.Len())
+		assert.Equal(t, 20, b2.Root().Weight)
+
+		_, ok := handle.Get(b.Root(), 1)
+		assert.True(t, ok, "original should be unchanged")
+
+		b3, n := b2.Insert(2, "B", 100)
+		assert.Nil(t, n, "duplicate key should be ignored")
+		assert.Equal(t, b2.Root(), b3.Root())
+	})
+
+	t.Run("MaxTreap", func(t *testing.T) {
+		h := treap.Handle{
+			CompareKeys:    treap.IntComparator,
+			CompareWeights: treap.MaxTreap(treap.IntComparator),
+		}
+
+		b := treap.NewBounded(h, 2)
+		for i, w := range []int{5, 1, 9, 3} {
+			b, _ = b.Insert(i, nil, w)
+		}
+
+		var got []int
+		for it := h.Iter(b.Root()); it.Node != nil; it.Next() {
+			got = append(got, it.Weight.(int))
+		}
+
+		assert.ElementsMatch(t, []int{1, 3}, got)
+	})
+}
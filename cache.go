@@ -0,0 +1,181 @@
+package treap
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Cache is a thread-safe, capacity-bounded cache.  Entries are looked up using the
+// treap's key ordering, and the entry with the lowest weight is evicted when the cache
+// overflows.  The eviction policy is determined by how weights are assigned on access;
+// see NewLRU and NewLFU.
+//
+// Writers, including Get, are serialized.  Peek, Len and Snapshot read a consistent
+// snapshot of the cache, and are wait-free.
+type Cache struct {
+	h     Handle
+	cap   int
+	touch func(old interface{}, tick uint64) interface{}
+	ptr   unsafe.Pointer // *cacheState
+
+	mu   sync.Mutex
+	tick uint64 // logical clock, incremented on each access
+}
+
+// cacheState is an immutable snapshot of a cache.
+type cacheState struct {
+	root *Node
+	len  int
+}
+
+// lfuWeight orders LFU entries by hit count, then by recency.
+type lfuWeight struct {
+	hits, tick uint64
+}
+
+// NewLRU returns a cache that holds at most capacity entries, and evicts the least
+// recently used entry when full.  Entry weights are the logical time of their last
+// access, as a uint64.
+func NewLRU(compareKeys Comparator, capacity int) *Cache {
+	return newCache(Handle{
+		CompareKeys:    compareKeys,
+		CompareWeights: UInt64Comparator,
+	}, capacity, func(_ interface{}, tick uint64) interface{} {
+		return tick
+	})
+}
+
+// NewLFU returns a cache that holds at most capacity entries, and evicts the least
+// frequently used entry when full.  Ties are broken by evicting the least recently
+// used entry.
+func NewLFU(compareKeys Comparator, capacity int) *Cache {
+	return newCache(Handle{
+		CompareKeys:    compareKeys,
+		CompareWeights: compareLFU,
+	}, capacity, func(old interface{}, tick uint64) interface{} {
+		var hits uint64
+		if old != nil {
+			hits = old.(lfuWeight).hits
+		}
+
+		return lfuWeight{hits: hits + 1, tick: tick}
+	})
+}
+
+func newCache(h Handle, capacity int, touch func(interface{}, uint64) interface{}) *Cache {
+	if capacity <= 0 {
+		panic("treap: cache capacity must be positive")
+	}
+
+	return &Cache{
+		h:     h,
+		cap:   capacity,
+		touch: touch,
+		ptr:   unsafe.Pointer(&cacheState{}),
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *Cache) Len() int {
+	return c.load().len
+}
+
+// Snapshot returns the root of the cache's treap.  The root is the next entry to be
+// evicted.
+func (c *Cache) Snapshot() *Node {
+	return c.load().root
+}
+
+// Peek returns the value for the specified key without counting it as an access.
+func (c *Cache) Peek(key interface{}) (interface{}, bool) {
+	return c.h.Get(c.load().root, key)
+}
+
+// Get returns the value for the specified key, and records the access.
+func (c *Cache) Get(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.load()
+	n, ok := c.h.GetNode(s.root, key)
+	if !ok {
+		return nil, false
+	}
+
+	root, _ := c.h.SetWeight(s.root, key, c.next(n.Weight))
+	c.store(&cacheState{root: root, len: s.len})
+
+	return n.Value, true
+}
+
+// Set a value, recording an access.  If the key is absent and the cache is full, the
+// entry with the lowest weight is evicted to make room.  The evicted node is the former
+// root of the treap; its Left and Right fields SHOULD be ignored.  Returns false if no
+// entry was evicted.
+func (c *Cache) Set(key, value interface{}) (evicted *Node, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.load()
+	new := &cacheState{root: s.root, len: s.len}
+
+	var weight interface{}
+	if n, found := c.h.GetNode(s.root, key); found {
+		weight = n.Weight
+	} else if new.len >= c.cap {
+		evicted, ok = new.root, true
+		_, new.root = c.h.Pop(new.root)
+		new.len--
+	}
+
+	var created bool
+	if new.root, created = c.h.Upsert(new.root, key, value, c.next(weight)); created {
+		new.len++
+	}
+
+	c.store(new)
+	return
+}
+
+// Delete an entry, returning false if it was not present.
+func (c *Cache) Delete(key interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.load()
+	if _, ok := c.h.GetNode(s.root, key); !ok {
+		return false
+	}
+
+	c.store(&cacheState{
+		root: c.h.Delete(s.root, key),
+		len:  s.len - 1,
+	})
+
+	return true
+}
+
+// next returns the weight of an entry after it is accessed.  The caller MUST hold c.mu.
+func (c *Cache) next(old interface{}) interface{} {
+	c.tick++
+	return c.touch(old, c.tick)
+}
+
+func (c *Cache) load() *cacheState {
+	return (*cacheState)(atomic.LoadPointer(&c.ptr))
+}
+
+// store a new state.  The caller MUST hold c.mu.
+func (c *Cache) store(s *cacheState) {
+	atomic.StorePointer(&c.ptr, unsafe.Pointer(s))
+}
+
+func compareLFU(a, b interface{}) int {
+	wa, wb := a.(lfuWeight), b.(lfuWeight)
+	if wa.hits != wb.hits {
+		return UInt64Comparator(wa.hits, wb.hits)
+	}
+
+	return UInt64Comparator(wa.tick, wb.tick)
+}
@@ -0,0 +1,169 @@
+package treap_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	t.Parallel()
+
+	c := treap.NewLRU(treap.StringComparator, 2)
+
+	_, ok := c.Set("a", 1)
+	assert.False(t, ok, "should not evict below capacity")
+	_, ok = c.Set("b", 2)
+	assert.False(t, ok, "should not evict at capacity")
+
+	v, ok := c.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	// "b" is now the least recently used entry
+	evicted, ok := c.Set("c", 3)
+	require.True(t, ok)
+	assert.Equal(t, "b", evicted.Key)
+	assert.Equal(t, 2, c.Len())
+
+	// Peek does not count as an access, so "a" is evicted next
+	_, ok = c.Peek("a")
+	require.True(t, ok)
+	evicted, ok = c.Set("d", 4)
+	require.True(t, ok)
+	assert.Equal(t, "a", evicted.Key)
+
+	// updating an existing key never evicts
+	_, ok = c.Set("c", 30)
+	assert.False(t, ok)
+	v, _ = c.Peek("c")
+	assert.Equal(t, 30, v)
+	assert.Equal(t, "d", c.Snapshot().Key)
+
+	assert.True(t, c.Delete("d"))
+	assert.False(t, c.Delete("d"))
+	assert.Equal(t, 1, c.Len())
+}
+
+func TestLFU(t *testing.T) {
+	t.Parallel()
+
+	c := treap.NewLFU(treap.StringComparator, 3)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+
+	for i := 0; i < 3; i++ {
+		c.Get("a")
+	}
+	c.Get("c")
+
+	// "b" has the fewest hits
+	evicted, ok := c.Set("d", 4)
+	require.True(t, ok)
+	assert.Equal(t, "b", evicted.Key)
+
+	// "c" has two hits, whereas "d" has one
+	evicted, ok = c.Set("e", 5)
+	require.True(t, ok)
+	assert.Equal(t, "d", evicted.Key)
+
+	// "c" and "e" tie once "e" is accessed; the least recently used is evicted
+	c.Get("e")
+	evicted, ok = c.Set("f", 6)
+	require.True(t, ok)
+	assert.Equal(t, "c", evicted.Key)
+
+	_, ok = c.Peek("a")
+	assert.True(t, ok, "most frequently used entry should survive")
+}
+
+func TestCache_Capacity(t *testing.T) {
+	t.Parallel()
+
+	assert.Panics(t, func() { treap.NewLRU(treap.StringComparator, 0) })
+}
+
+func TestCache_Model(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		desc string
+		new  func(treap.Comparator, int) *treap.Cache
+		less func(a, b cacheEntry) bool // reports whether a is evicted before b
+	}{
+		{"LRU", treap.NewLRU, func(a, b cacheEntry) bool {
+			return a.tick < b.tick
+		}},
+		{"LFU", treap.NewLFU, func(a, b cacheEntry) bool {
+			return a.hits < b.hits || (a.hits == b.hits && a.tick < b.tick)
+		}},
+	} {
+		const capacity = 32
+
+		var (
+			rng   = rand.New(rand.NewSource(1))
+			c     = tt.new(treap.IntComparator, capacity)
+			model = make(map[int]cacheEntry)
+			tick  int
+		)
+
+		for i := 0; i < 5000; i++ {
+			k := rng.Intn(capacity * 2)
+
+			if rng.Intn(2) == 0 {
+				v, ok := c.Get(k)
+				e, want := model[k]
+				require.Equal(t, want, ok, "%s: Get(%d)", tt.desc, k)
+
+				if ok {
+					require.Equal(t, e.value, v, "%s: Get(%d)", tt.desc, k)
+
+					tick++
+					e.hits++
+					e.tick = tick
+					model[k] = e
+				}
+
+				continue
+			}
+
+			var wantEvicted interface{}
+			e, found := model[k]
+			if !found && len(model) == capacity {
+				var victim int
+				first := true
+				for key, e := range model {
+					if first || tt.less(e, model[victim]) {
+						victim, first = key, false
+					}
+				}
+
+				wantEvicted = victim
+				delete(model, victim)
+			}
+
+			tick++
+			e.value, e.hits, e.tick = i, e.hits+1, tick
+			model[k] = e
+
+			evicted, ok := c.Set(k, i)
+			require.Equal(t, wantEvicted != nil, ok, "%s: Set(%d)", tt.desc, k)
+			if ok {
+				require.Equal(t, wantEvicted, evicted.Key, "%s: wrong entry evicted", tt.desc)
+			}
+
+			require.Equal(t, len(model), c.Len(), tt.desc)
+		}
+	}
+}
+
+// cacheEntry is the reference model's view of a cache entry.
+type cacheEntry struct {
+	value      interface{}
+	hits, tick int
+}
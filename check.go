@@ -0,0 +1,202 @@
+package treap
+
+import "fmt"
+
+// Comparator properties checked by CheckComparator.
+const (
+	PropertyRange        = "range"
+	PropertyPanic        = "panic"
+	PropertyReflexivity  = "reflexivity"
+	PropertyAntisymmetry = "antisymmetry"
+	PropertyTransitivity = "transitivity"
+	PropertyNilHandling  = "nil handling"
+)
+
+// Violation is a counterexample to one of the properties that a Comparator must
+// satisfy in order to maintain a valid treap.
+type Violation struct {
+	Property string        // one of the Property* constants
+	Values   []interface{} // the values for which the property does not hold
+	Detail   string        // human-readable description of the violation
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Property, v.Detail)
+}
+
+// CheckComparator tests the comparator f against n sample values drawn from gen, and
+// returns the violations it finds.  At most one counterexample is reported for each
+// property.  The following properties are checked:
+//
+//   - range:  f only ever returns -1, 0 or 1.
+//   - panic:  f does not panic on non-nil values.
+//   - reflexivity:  f(a, a) == 0.
+//   - antisymmetry:  f(a, b) == -f(b, a).
+//   - transitivity:  f(a, b) <= 0 and f(b, c) <= 0 implies f(a, c) <= 0, with
+//     equality only if both premises are equalities.
+//   - nil handling:  either every comparison involving nil panics (see NilRejected),
+//     or nil is equal to itself and consistently ordered before (see NilFirst) or
+//     after (see NilLast) all other values.
+//
+// The transitivity check is O(n^3), so n should be kept small (e.g. 100).  Samples
+// should include duplicates, as these are needed to exercise equality.
+func CheckComparator(f Comparator, gen func() interface{}, n int) []Violation {
+	chk := checker{f: f, found: make(map[string]bool)}
+
+	samples := make([]interface{}, n)
+	for i := range samples {
+		samples[i] = gen()
+	}
+
+	chk.checkNil(samples)
+
+	// results[i][j] holds f(samples[i], samples[j])
+	results := make([][]int, n)
+	for i, a := range samples {
+		results[i] = make([]int, n)
+		for j, b := range samples {
+			res, ok := chk.compare(a, b)
+			if !ok {
+				return chk.violations
+			}
+
+			results[i][j] = res
+		}
+	}
+
+	for i, a := range samples {
+		if results[i][i] != 0 {
+			chk.report(PropertyReflexivity, fmt.Sprintf("f(%v, %v) == %d", a, a, results[i][i]), a)
+		}
+
+		for j, b := range samples {
+			if results[i][j] != -results[j][i] {
+				chk.report(PropertyAntisymmetry, fmt.Sprintf("f(%v, %v) == %d, but f(%v, %v) == %d",
+					a, b, results[i][j], b, a, results[j][i]), a, b)
+			}
+		}
+	}
+
+	for i, a := range samples {
+		for j, b := range samples {
+			if results[i][j] > 0 {
+				continue
+			}
+
+			for k, c := range samples {
+				if results[j][k] > 0 {
+					continue
+				}
+
+				want := -1
+				if results[i][j] == 0 && results[j][k] == 0 {
+					want = 0
+				}
+
+				if got := results[i][k]; (want == 0 && got != 0) || (want < 0 && got >= 0) {
+					chk.report(PropertyTransitivity, fmt.Sprintf("f(%v, %v) == %d and f(%v, %v) == %d, but f(%v, %v) == %d",
+						a, b, results[i][j], b, c, results[j][k], a, c, got), a, b, c)
+				}
+			}
+		}
+	}
+
+	return chk.violations
+}
+
+type checker struct {
+	f          Comparator
+	found      map[string]bool
+	violations []Violation
+}
+
+func (c *checker) report(property, detail string, vs ...interface{}) {
+	if c.found[property] {
+		return
+	}
+
+	c.found[property] = true
+	c.violations = append(c.violations, Violation{
+		Property: property,
+		Values:   vs,
+		Detail:   detail,
+	})
+}
+
+// compare a and b, reporting panics and out-of-range results.  The returned bool is
+// false if the comparator panicked.
+func (c *checker) compare(a, b interface{}) (int, bool) {
+	res, p := c.call(a, b)
+	if p != nil {
+		c.report(PropertyPanic, fmt.Sprintf("f(%v, %v) panicked: %v", a, b, p), a, b)
+		return 0, false
+	}
+
+	if res < -1 || res > 1 {
+		c.report(PropertyRange, fmt.Sprintf("f(%v, %v) == %d", a, b, res), a, b)
+	}
+
+	return res, true
+}
+
+func (c *checker) call(a, b interface{}) (res int, p interface{}) {
+	defer func() {
+		p = recover()
+	}()
+
+	return c.f(a, b), nil
+}
+
+func (c *checker) checkNil(samples []interface{}) {
+	res, p := c.call(nil, nil)
+	if p != nil { // nils rejected; every comparison involving nil must panic
+		for _, x := range samples {
+			if res, p := c.call(nil, x); p == nil {
+				c.report(PropertyNilHandling, fmt.Sprintf("f(nil, nil) panicked, but f(nil, %v) == %d", x, res), nil, x)
+				return
+			}
+
+			if res, p := c.call(x, nil); p == nil {
+				c.report(PropertyNilHandling, fmt.Sprintf("f(nil, nil) panicked, but f(%v, nil) == %d", x, res), x, nil)
+				return
+			}
+		}
+
+		return
+	}
+
+	if res != 0 {
+		c.report(PropertyNilHandling, fmt.Sprintf("f(nil, nil) == %d", res), nil, nil)
+		return
+	}
+
+	var order int // -1 if nil is first, 1 if nil is last
+	for _, x := range samples {
+		left, p := c.call(nil, x)
+		if p != nil {
+			c.report(PropertyNilHandling, fmt.Sprintf("f(nil, %v) panicked: %v", x, p), nil, x)
+			return
+		}
+
+		right, p := c.call(x, nil)
+		if p != nil {
+			c.report(PropertyNilHandling, fmt.Sprintf("f(%v, nil) panicked: %v", x, p), x, nil)
+			return
+		}
+
+		switch {
+		case left == 0 || left != -right:
+			c.report(PropertyNilHandling, fmt.Sprintf("f(nil, %v) == %d, but f(%v, nil) == %d",
+				x, left, x, right), nil, x)
+			return
+
+		case order == 0:
+			order = left
+
+		case order != left:
+			c.report(PropertyNilHandling, fmt.Sprintf("nil is ordered inconsistently: f(nil, %v) == %d",
+				x, left), nil, x)
+			return
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package treap_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckComparator(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		comp treap.Comparator
+	}{{
+		desc: "IntComparator",
+		comp: treap.IntComparator,
+	}, {
+		desc: "MaxTreap",
+		comp: treap.MaxTreap(treap.IntComparator),
+	}, {
+		desc: "NilLast",
+		comp: treap.NilLast(treap.IntComparator),
+	}, {
+		desc: "NilRejected",
+		comp: treap.NilRejected(treap.IntComparator),
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			vs := treap.CheckComparator(tc.comp, genInt(rand.New(rand.NewSource(1))), 50)
+			assert.Empty(t, vs)
+		})
+	}
+}
+
+func TestCheckComparator_StringComparators(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		comp treap.Comparator
+		gen  func(*rand.Rand) string
+	}{{
+		desc: "NaturalStringComparator",
+		comp: treap.NaturalStringComparator,
+		gen: func(rng *rand.Rand) string {
+			return []string{"a", "A", "0", "01", "1", "10", "b"}[rng.Intn(7)] +
+				[]string{"", "0", "2", "x"}[rng.Intn(4)]
+		},
+	}, {
+		desc: "FoldStringComparator",
+		comp: treap.FoldStringComparator,
+		gen: func(rng *rand.Rand) string {
+			return []string{"a", "A", "_", "k", "K", "ß", "Σ", "ς"}[rng.Intn(8)] +
+				[]string{"", "b", "B"}[rng.Intn(3)]
+		},
+	}, {
+		desc: "SemverComparator",
+		comp: treap.SemverComparator,
+		gen: func(rng *rand.Rand) string {
+			return []string{"1.0.0", "v1.0.0", "1.10.0", "1.2.0", "bogus"}[rng.Intn(5)] +
+				[]string{"", "-alpha", "-alpha.1", "-1", "+build"}[rng.Intn(5)]
+		},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			vs := treap.CheckComparator(tc.comp, func() interface{} {
+				return tc.gen(rng)
+			}, 60)
+			assert.Empty(t, vs)
+		})
+	}
+}
+
+func TestCheckComparator_Violations(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc     string
+		property string
+		comp     treap.Comparator
+	}{{
+		desc:     "unnormalized result",
+		property: treap.PropertyRange,
+		comp: treap.NilFirst(func(a, b interface{}) int {
+			return a.(int) - b.(int)
+		}),
+	}, {
+		desc:     "wrong type",
+		property: treap.PropertyPanic,
+		comp:     treap.NilFirst(treap.StringComparator),
+	}, {
+		desc:     "irreflexive",
+		property: treap.PropertyReflexivity,
+		comp: treap.NilFirst(func(a, b interface{}) int {
+			if a.(int) <= b.(int) {
+				return -1
+			}
+			return 1
+		}),
+	}, {
+		desc:     "not antisymmetric",
+		property: treap.PropertyAntisymmetry,
+		comp: treap.NilFirst(func(a, b interface{}) int {
+			if a.(int) == b.(int) {
+				return 0
+			}
+			return 1
+		}),
+	}, {
+		desc:     "not transitive",
+		property: treap.PropertyTransitivity,
+		comp: treap.NilFirst(func(a, b interface{}) int {
+			// rock-paper-scissors
+			x, y := a.(int)%3, b.(int)%3
+			switch {
+			case x == y:
+				return 0
+			case (x+1)%3 == y:
+				return -1
+			default:
+				return 1
+			}
+		}),
+	}, {
+		desc:     "nil not equal to itself",
+		property: treap.PropertyNilHandling,
+		comp: func(a, b interface{}) int {
+			if a == nil {
+				return -1
+			}
+			if b == nil {
+				return 1
+			}
+			return treap.IntComparator(a, b)
+		},
+	}, {
+		desc:     "nil ordered inconsistently",
+		property: treap.PropertyNilHandling,
+		comp: func(a, b interface{}) int {
+			switch {
+			case a == nil && b == nil:
+				return 0
+			case a == nil:
+				return 1 - 2*(b.(int)%2)
+			case b == nil:
+				return -1 + 2*(a.(int)%2)
+			}
+			return treap.IntComparator(a, b)
+		},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			vs := treap.CheckComparator(tc.comp, genInt(rand.New(rand.NewSource(1))), 30)
+			require.NotEmpty(t, vs)
+
+			var found bool
+			for _, v := range vs {
+				found = found || v.Property == tc.property
+				assert.NotEmpty(t, v.Values, "violation should report a counterexample")
+			}
+
+			assert.True(t, found, "expected %s violation, got %v", tc.property, vs)
+		})
+	}
+}
+
+func genInt(rng *rand.Rand) func() interface{} {
+	return func() interface{} {
+		return rng.Intn(20)
+	}
+}
@@ -0,0 +1,30 @@
+package treap
+
+import "time"
+
+// Clock is a source of time.  It allows time-based types such as Scheduler to be
+// tested without sleeping.
+type Clock interface {
+	Now() time.Time
+
+	// AfterFunc calls f in its own goroutine after duration d has elapsed.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a pending call to a function, created by Clock.AfterFunc.
+type Timer interface {
+	// Stop prevents the function from being called.  It returns false if the function
+	// has already been called, or the timer has already been stopped.
+	Stop() bool
+}
+
+// SystemClock is a Clock backed by the time package.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
@@ -0,0 +1,116 @@
+package treap_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemClock(t *testing.T) {
+	t.Parallel()
+
+	fired := make(chan struct{})
+	treap.SystemClock.AfterFunc(time.Millisecond, func() { close(fired) })
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("timer did not fire")
+	}
+
+	assert.True(t, treap.SystemClock.AfterFunc(time.Hour, func() {}).Stop(),
+		"pending timer should stop")
+}
+
+// fakeClock is a treap.Clock that only advances when told to.  Timers are fired
+// synchronously by Advance.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	c    *fakeClock
+	at   time.Time
+	f    func()
+	done bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) treap.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{c: c, at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance the clock by d, firing due timers in order.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	c.mu.Unlock()
+
+	for {
+		c.mu.Lock()
+
+		var next *fakeTimer
+		for _, t := range c.timers {
+			if !t.done && !t.at.After(target) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+
+		if next == nil {
+			c.now = target
+			c.mu.Unlock()
+			return
+		}
+
+		if next.at.After(c.now) {
+			c.now = next.at
+		}
+		next.done = true
+		c.mu.Unlock()
+
+		next.f()
+	}
+}
+
+// Pending returns the number of timers that have not fired or been stopped.
+func (c *fakeClock) Pending() (n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, t := range c.timers {
+		if !t.done {
+			n++
+		}
+	}
+
+	return
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+
+	stopped := !t.done
+	t.done = true
+	return stopped
+}
@@ -0,0 +1,102 @@
+package treap
+
+import "sync"
+
+// Combiner reduces CAS contention on an Atomic treap using flat combining.  Concurrent
+// Upsert and Delete requests are queued, and a single goroutine (the combiner) applies
+// the whole batch to the current root in one pass before publishing it with a single
+// CAS.  Other goroutines simply wait for their results, rather than repeatedly
+// path-copying the treap and failing the CAS.
+//
+// Writes made directly to the Atomic are supported, but will cause the combiner to
+// re-apply its batch.  Reads from the Atomic remain wait-free.
+type Combiner struct {
+	a *Atomic
+
+	mu    sync.Mutex // guards queue
+	queue []*combineReq
+
+	combining sync.Mutex // held by the current combiner
+}
+
+type combineOp uint8
+
+const (
+	combineUpsert combineOp = iota
+	combineDelete
+)
+
+type combineReq struct {
+	op               combineOp
+	key, val, weight interface{}
+
+	// written by the combiner
+	result, done bool
+}
+
+// NewCombiner returns a combining writer for a.
+func NewCombiner(a *Atomic) *Combiner {
+	return &Combiner{a: a}
+}
+
+// Upsert updates an element, creating one if it is missing.
+func (c *Combiner) Upsert(key, val, weight interface{}) (created bool) {
+	return c.do(&combineReq{
+		op:     combineUpsert,
+		key:    key,
+		val:    val,
+		weight: weight,
+	})
+}
+
+// Delete an element, returning false if it was not present.
+func (c *Combiner) Delete(key interface{}) (deleted bool) {
+	return c.do(&combineReq{
+		op:  combineDelete,
+		key: key,
+	})
+}
+
+func (c *Combiner) do(r *combineReq) bool {
+	c.mu.Lock()
+	c.queue = append(c.queue, r)
+	c.mu.Unlock()
+
+	c.combining.Lock()
+	defer c.combining.Unlock()
+
+	// r may have been served by the previous combiner
+	if !r.done {
+		c.mu.Lock()
+		batch := c.queue
+		c.queue = nil
+		c.mu.Unlock()
+
+		c.apply(batch)
+	}
+
+	return r.result
+}
+
+func (c *Combiner) apply(batch []*combineReq) {
+	h := c.a.h
+
+	c.a.Update(func(root *Node) *Node {
+		for _, r := range batch {
+			switch r.op {
+			case combineUpsert:
+				root, r.result = h.Upsert(root, r.key, r.val, r.weight)
+			case combineDelete:
+				if _, r.result = h.GetNode(root, r.key); r.result {
+					root = h.Delete(root, r.key)
+				}
+			}
+		}
+
+		return root
+	})
+
+	for _, r := range batch {
+		r.done = true
+	}
+}
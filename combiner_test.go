@@ -0,0 +1,54 @@
+package treap_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombiner(t *testing.T) {
+	t.Parallel()
+
+	a := treap.NewAtomic(handle)
+	c := treap.NewCombiner(a)
+	cs := mkTestCases(100)
+
+	var wg sync.WaitGroup
+	created := make([]bool, len(cs))
+	for i, tc := range cs {
+		wg.Add(1)
+		go func(i int, tc testCase) {
+			defer wg.Done()
+			created[i] = c.Upsert(tc.key, tc.value, tc.weight)
+		}(i, tc)
+	}
+	wg.Wait()
+
+	for i, tc := range cs {
+		assert.True(t, created[i], "key %d should have been created", tc.key)
+	}
+	testOthers(t, handle, a.Load(), cs)
+
+	assert.False(t, c.Upsert(cs[0].key, "updated", cs[0].weight))
+	v, _ := handle.Get(a.Load(), cs[0].key)
+	assert.Equal(t, "updated", v)
+
+	deleted := make([]bool, len(cs))
+	for i, tc := range cs {
+		wg.Add(1)
+		go func(i int, tc testCase) {
+			defer wg.Done()
+			deleted[i] = c.Delete(tc.key)
+		}(i, tc)
+	}
+	wg.Wait()
+
+	for i, tc := range cs {
+		assert.True(t, deleted[i], "key %d should have been deleted", tc.key)
+	}
+	assert.Nil(t, a.Load())
+
+	assert.False(t, c.Delete(cs[0].key), "deleting a missing key should report false")
+}
@@ -1,13 +1,24 @@
 package treap
 
 import (
+	"bytes"
+	"errors"
+	"math/big"
+	"net/netip"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 	"unsafe"
 )
 
+// ErrNilValue is the panic value raised by comparators wrapped with NilRejected.
+var ErrNilValue = errors.New("treap: nil value passed to comparator")
+
 // Comparator establishes ordering between two elements.
 // It returns -1 if a < b, 0 if a == b, and 1 if a > b.
-// Nil values are treated as -Inf.
+// The built-in comparators treat nil values as -Inf.  This convention can be changed
+// using NilFirst, NilLast and NilRejected.
 type Comparator func(a, b interface{}) int
 
 // MaxTreap wraps a comparator, resulting in a treap with max-heap ordering.
@@ -17,9 +28,62 @@ func MaxTreap(f Comparator) Comparator {
 	}
 }
 
+// NilFirst wraps a comparator such that nil values are treated as -Inf, i.e. they
+// are ordered before any other value.  Two nil values are equal.  Non-nil values are
+// passed to f.
+func NilFirst(f Comparator) Comparator {
+	return func(a, b interface{}) int {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		case b == nil:
+			return 1
+		}
+
+		return f(a, b)
+	}
+}
+
+// NilLast wraps a comparator such that nil values are treated as +Inf, i.e. they
+// are ordered after any other value.  Two nil values are equal.  Non-nil values are
+// passed to f.
+//
+// This is useful for optional weights, such as deadlines, where nil-weighted items
+// should never be popped before the others.
+func NilLast(f Comparator) Comparator {
+	return func(a, b interface{}) int {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return 1
+		case b == nil:
+			return -1
+		}
+
+		return f(a, b)
+	}
+}
+
+// NilRejected wraps a comparator such that it panics with ErrNilValue if either
+// argument is nil.
+func NilRejected(f Comparator) Comparator {
+	return func(a, b interface{}) int {
+		if a == nil || b == nil {
+			panic(ErrNilValue)
+		}
+
+		return f(a, b)
+	}
+}
+
 // IntComparator compares integers.  Nil values are considered infinite.
 func IntComparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -42,6 +106,8 @@ func IntComparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func StringComparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -74,6 +140,8 @@ func StringComparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func Int8Comparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -96,6 +164,8 @@ func Int8Comparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func Int16Comparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -118,6 +188,8 @@ func Int16Comparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func Int32Comparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -140,6 +212,8 @@ func Int32Comparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func Int64Comparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -162,6 +236,8 @@ func Int64Comparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func UIntComparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -184,6 +260,8 @@ func UIntComparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func UInt8Comparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -206,6 +284,8 @@ func UInt8Comparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func UInt16Comparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -228,6 +308,8 @@ func UInt16Comparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func UInt32Comparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -250,6 +332,8 @@ func UInt32Comparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func UInt64Comparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -272,6 +356,8 @@ func UInt64Comparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func Float32Comparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -294,6 +380,8 @@ func Float32Comparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func Float64Comparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -316,6 +404,8 @@ func Float64Comparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func BytesComparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -335,6 +425,8 @@ func BytesComparator(a, b interface{}) int {
 // Nil values are treated as infinite.
 func TimeComparator(a, b interface{}) int {
 	switch {
+	case a == nil && b == nil:
+		return 0
 	case a == nil:
 		return -1 // N.B.:  treap is a min-heap by default
 	case b == nil:
@@ -353,3 +445,494 @@ func TimeComparator(a, b interface{}) int {
 		return 0
 	}
 }
+
+// DurationComparator provides a basic comparison on time.Duration.
+// Nil values are treated as infinite.
+func DurationComparator(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1 // N.B.:  treap is a min-heap by default
+	case b == nil:
+		return 1
+	}
+
+	aAsserted := a.(time.Duration)
+	bAsserted := b.(time.Duration)
+	switch {
+	case aAsserted < bAsserted:
+		return -1
+	case aAsserted > bAsserted:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// BoolComparator provides a basic comparison on bool.  False is ordered before true.
+// Nil values are treated as infinite.
+func BoolComparator(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1 // N.B.:  treap is a min-heap by default
+	case b == nil:
+		return 1
+	}
+
+	aAsserted := a.(bool)
+	bAsserted := b.(bool)
+	switch {
+	case aAsserted == bAsserted:
+		return 0
+	case bAsserted:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// UUIDComparator provides a bytewise comparison on [16]byte, the underlying type of
+// most UUID implementations.
+// Nil values are treated as infinite.
+func UUIDComparator(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1 // N.B.:  treap is a min-heap by default
+	case b == nil:
+		return 1
+	}
+
+	aAsserted := a.([16]byte)
+	bAsserted := b.([16]byte)
+
+	return bytes.Compare(aAsserted[:], bAsserted[:])
+}
+
+// BigIntComparator provides a basic comparison on *big.Int.
+// Nil values are treated as infinite.
+func BigIntComparator(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1 // N.B.:  treap is a min-heap by default
+	case b == nil:
+		return 1
+	}
+
+	return a.(*big.Int).Cmp(b.(*big.Int))
+}
+
+// BigFloatComparator provides a basic comparison on *big.Float.
+// Nil values are treated as infinite.
+func BigFloatComparator(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1 // N.B.:  treap is a min-heap by default
+	case b == nil:
+		return 1
+	}
+
+	return a.(*big.Float).Cmp(b.(*big.Float))
+}
+
+// AddrComparator provides a basic comparison on netip.Addr.  The zero Addr is
+// ordered first, followed by IPv4 addresses and IPv6 addresses.  IPv6 addresses
+// with zones are ordered after their zone-less counterparts.
+// Nil values are treated as infinite.
+func AddrComparator(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1 // N.B.:  treap is a min-heap by default
+	case b == nil:
+		return 1
+	}
+
+	return a.(netip.Addr).Compare(b.(netip.Addr))
+}
+
+// PrefixComparator provides a comparison on netip.Prefix that is suitable for
+// containment queries.  Prefixes are ordered by their masked address, then by prefix
+// length, such that a prefix is immediately followed by the prefixes it contains.
+// For example:
+//
+//	10.0.0.0/8 < 10.0.0.0/16 < 10.0.0.0/24 < 10.1.0.0/16 < 11.0.0.0/8
+//
+// Prefixes that share a masked address and length, but are not canonical (e.g.
+// 10.1.2.3/8), are ordered by their unmasked address.
+// Nil values are treated as infinite.
+func PrefixComparator(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1 // N.B.:  treap is a min-heap by default
+	case b == nil:
+		return 1
+	}
+
+	aAsserted := a.(netip.Prefix)
+	bAsserted := b.(netip.Prefix)
+
+	if c := aAsserted.Masked().Addr().Compare(bAsserted.Masked().Addr()); c != 0 {
+		return c
+	}
+
+	switch {
+	case aAsserted.Bits() < bAsserted.Bits():
+		return -1
+	case aAsserted.Bits() > bAsserted.Bits():
+		return 1
+	default:
+		return aAsserted.Addr().Compare(bAsserted.Addr())
+	}
+}
+
+// NaturalStringComparator compares strings in natural order, i.e. runs of ASCII digits
+// are compared by their numeric value, such that "file2" < "file10".  All other bytes
+// are compared as in StringComparator.  Strings that are equal in natural order, but
+// which differ in leading zeros (e.g. "file01" and "file1"), are ordered bytewise.
+// Nil values are treated as infinite.
+func NaturalStringComparator(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1 // N.B.:  treap is a min-heap by default
+	case b == nil:
+		return 1
+	}
+
+	s1 := a.(string)
+	s2 := b.(string)
+
+	var i, j int
+	for i < len(s1) && j < len(s2) {
+		if isDigit(s1[i]) && isDigit(s2[j]) {
+			var n1, n2 string
+			n1, i = digitRun(s1, i)
+			n2, j = digitRun(s2, j)
+
+			if c := compareNumeric(n1, n2); c != 0 {
+				return c
+			}
+
+			continue
+		}
+
+		switch {
+		case s1[i] < s2[j]:
+			return -1
+		case s1[i] > s2[j]:
+			return 1
+		}
+
+		i++
+		j++
+	}
+
+	switch {
+	case i < len(s1):
+		return 1
+	case j < len(s2):
+		return -1
+	default:
+		return StringComparator(s1, s2)
+	}
+}
+
+// FoldStringComparator compares strings under Unicode simple case folding, such
+// that "apple" < "Zebra".  Two strings are equal if and only if strings.EqualFold
+// reports them as such, so keys that differ only in case will collide in a treap.
+// Nil values are treated as infinite.
+func FoldStringComparator(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1 // N.B.:  treap is a min-heap by default
+	case b == nil:
+		return 1
+	}
+
+	s1 := a.(string)
+	s2 := b.(string)
+
+	for s1 != "" && s2 != "" {
+		r1, n1 := decodeRune(s1)
+		r2, n2 := decodeRune(s2)
+
+		r1, r2 = foldRune(r1), foldRune(r2)
+		switch {
+		case r1 < r2:
+			return -1
+		case r1 > r2:
+			return 1
+		}
+
+		s1, s2 = s1[n1:], s2[n2:]
+	}
+
+	switch {
+	case s1 != "":
+		return 1
+	case s2 != "":
+		return -1
+	default:
+		return 0
+	}
+}
+
+// SemverComparator compares semantic version strings (see https://semver.org), with
+// an optional "v" prefix.  Versions are ordered by semver precedence, such that
+// "1.0.0-alpha" < "1.0.0-beta.2" < "1.0.0-beta.11" < "1.0.0" < "1.10.0".
+//
+// Versions with equal precedence that are nevertheless distinct strings (e.g. those
+// that differ in build metadata) are ordered bytewise, so that they do not collide in
+// a treap.  Invalid version strings are ordered before valid ones, and bytewise
+// amongst themselves.
+// Nil values are treated as infinite.
+func SemverComparator(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1 // N.B.:  treap is a min-heap by default
+	case b == nil:
+		return 1
+	}
+
+	s1 := a.(string)
+	s2 := b.(string)
+
+	v1, ok1 := parseSemver(s1)
+	v2, ok2 := parseSemver(s2)
+	switch {
+	case !ok1 && !ok2:
+		return StringComparator(s1, s2)
+	case !ok1:
+		return -1
+	case !ok2:
+		return 1
+	}
+
+	if c := v1.compare(v2); c != 0 {
+		return c
+	}
+
+	return StringComparator(s1, s2)
+}
+
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
+
+// digitRun returns the run of digits starting at s[i], stripped of leading zeros,
+// along with the index of the first byte following the run.
+func digitRun(s string, i int) (string, int) {
+	for i < len(s)-1 && s[i] == '0' && isDigit(s[i+1]) {
+		i++
+	}
+
+	start := i
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+
+	return s[start:i], i
+}
+
+// compareNumeric compares two strings of decimal digits without leading zeros.
+func compareNumeric(n1, n2 string) int {
+	switch {
+	case len(n1) < len(n2):
+		return -1
+	case len(n1) > len(n2):
+		return 1
+	default:
+		return StringComparator(n1, n2)
+	}
+}
+
+func decodeRune(s string) (rune, int) {
+	if s[0] < utf8.RuneSelf {
+		return rune(s[0]), 1
+	}
+
+	return utf8.DecodeRuneInString(s)
+}
+
+// foldRune maps r onto a canonical member of its simple case-folding orbit.  The
+// result is the same for all runes that are equal under strings.EqualFold.
+func foldRune(r rune) rune {
+	if r < utf8.RuneSelf {
+		if 'A' <= r && r <= 'Z' {
+			r += 'a' - 'A'
+		}
+
+		return r
+	}
+
+	min, lower := r, rune(-1)
+	for f := unicode.SimpleFold(r); ; f = unicode.SimpleFold(f) {
+		if f < min {
+			min = f
+		}
+
+		if unicode.IsLower(f) && (lower < 0 || f < lower) {
+			lower = f
+		}
+
+		if f == r {
+			break
+		}
+	}
+
+	// Prefer the orbit's lower-case member, so that letters sort as they do on the
+	// ASCII path.  The result MUST lie in the orbit, which rules out unicode.ToLower:
+	// it maps 'İ' (U+0130) to 'i', although the two are not equal under folding.
+	if lower >= 0 {
+		return lower
+	}
+
+	return min
+}
+
+type semver struct {
+	major, minor, patch, pre string
+}
+
+func parseSemver(s string) (v semver, ok bool) {
+	s = strings.TrimPrefix(s, "v")
+
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		if !validIdentifiers(s[i+1:], false) {
+			return
+		}
+
+		s = s[:i]
+	}
+
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		if v.pre = s[i+1:]; !validIdentifiers(v.pre, true) {
+			return
+		}
+
+		s = s[:i]
+	}
+
+	v.major, s, _ = strings.Cut(s, ".")
+	v.minor, v.patch, _ = strings.Cut(s, ".")
+
+	ok = validNumeric(v.major) && validNumeric(v.minor) && validNumeric(v.patch)
+	return
+}
+
+func (v semver) compare(other semver) int {
+	if c := compareNumeric(v.major, other.major); c != 0 {
+		return c
+	}
+
+	if c := compareNumeric(v.minor, other.minor); c != 0 {
+		return c
+	}
+
+	if c := compareNumeric(v.patch, other.patch); c != 0 {
+		return c
+	}
+
+	// a version without a pre-release has higher precedence than one with
+	switch {
+	case v.pre == "" && other.pre == "":
+		return 0
+	case v.pre == "":
+		return 1
+	case other.pre == "":
+		return -1
+	}
+
+	p1, p2 := v.pre, other.pre
+	for p1 != "" && p2 != "" {
+		var id1, id2 string
+		id1, p1, _ = strings.Cut(p1, ".")
+		id2, p2, _ = strings.Cut(p2, ".")
+
+		if c := compareIdentifier(id1, id2); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case p1 != "":
+		return 1
+	case p2 != "":
+		return -1
+	default:
+		return 0
+	}
+}
+
+// compareIdentifier compares pre-release identifiers.  Numeric identifiers have lower
+// precedence than alphanumeric ones.
+func compareIdentifier(id1, id2 string) int {
+	num1, num2 := isNumeric(id1), isNumeric(id2)
+	switch {
+	case num1 && num2:
+		return compareNumeric(id1, id2)
+	case num1:
+		return -1
+	case num2:
+		return 1
+	default:
+		return StringComparator(id1, id2)
+	}
+}
+
+func isNumeric(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+
+	return s != ""
+}
+
+func validNumeric(s string) bool {
+	return isNumeric(s) && (s == "0" || s[0] != '0')
+}
+
+// validIdentifiers reports whether s is a non-empty, dot-separated list of semver
+// identifiers.  If strict is true, numeric identifiers must not have leading zeros.
+func validIdentifiers(s string, strict bool) bool {
+	for {
+		id, rest, more := strings.Cut(s, ".")
+		if id == "" {
+			return false
+		}
+
+		for i := 0; i < len(id); i++ {
+			if c := id[i]; !isDigit(c) && c != '-' &&
+				!('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') {
+				return false
+			}
+		}
+
+		if strict && isNumeric(id) && !validNumeric(id) {
+			return false
+		}
+
+		if !more {
+			return true
+		}
+
+		s = rest
+	}
+}
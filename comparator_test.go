@@ -1,8 +1,13 @@
 package treap_test
 
 import (
+	"math/big"
+	"net/netip"
+	"strings"
 	"testing"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/lthibault/treap"
 	"github.com/stretchr/testify/assert"
@@ -39,6 +44,73 @@ func TestMaxTreap(t *testing.T) {
 	}
 }
 
+func TestNilFirst(t *testing.T) {
+	t.Parallel()
+
+	comp := treap.NilFirst(treap.IntComparator)
+
+	for _, tc := range []struct {
+		desc string
+		test []interface{}
+	}{{
+		desc: "nil < 1",
+		test: []interface{}{nil, 1, -1},
+	}, {
+		desc: "1 > nil",
+		test: []interface{}{1, nil, 1},
+	}, {
+		desc: "nil == nil",
+		test: []interface{}{nil, nil, 0},
+	}, {
+		desc: "1 < 2",
+		test: []interface{}{1, 2, -1},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.test[2], comp(tc.test[0], tc.test[1]),
+				"constraint %s violated", tc.desc)
+		})
+	}
+}
+
+func TestNilLast(t *testing.T) {
+	t.Parallel()
+
+	comp := treap.NilLast(treap.IntComparator)
+
+	for _, tc := range []struct {
+		desc string
+		test []interface{}
+	}{{
+		desc: "nil > 1",
+		test: []interface{}{nil, 1, 1},
+	}, {
+		desc: "1 < nil",
+		test: []interface{}{1, nil, -1},
+	}, {
+		desc: "nil == nil",
+		test: []interface{}{nil, nil, 0},
+	}, {
+		desc: "1 < 2",
+		test: []interface{}{1, 2, -1},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.test[2], comp(tc.test[0], tc.test[1]),
+				"constraint %s violated", tc.desc)
+		})
+	}
+}
+
+func TestNilRejected(t *testing.T) {
+	t.Parallel()
+
+	comp := treap.NilRejected(treap.IntComparator)
+
+	assert.Equal(t, -1, comp(1, 2))
+	assert.PanicsWithValue(t, treap.ErrNilValue, func() { comp(nil, 1) })
+	assert.PanicsWithValue(t, treap.ErrNilValue, func() { comp(1, nil) })
+	assert.PanicsWithValue(t, treap.ErrNilValue, func() { comp(nil, nil) })
+}
+
 func TestIntComparator(t *testing.T) {
 	t.Parallel()
 
@@ -48,6 +120,9 @@ func TestIntComparator(t *testing.T) {
 	}{{
 		desc: "nil < 1",
 		test: []interface{}{nil, 1, -1},
+	}, {
+		desc: "nil == nil",
+		test: []interface{}{nil, nil, 0},
 	}, {
 		desc: "1 > nil",
 		test: []interface{}{1, nil, 1},
@@ -523,3 +598,478 @@ func TestFloat64Comparator(t *testing.T) {
 		})
 	}
 }
+
+func TestDurationComparator(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		test []interface{}
+	}{{
+		desc: "nil < 1s",
+		test: []interface{}{nil, time.Second, -1},
+	}, {
+		desc: "1s > nil",
+		test: []interface{}{time.Second, nil, 1},
+	}, {
+		desc: "1s == 1s",
+		test: []interface{}{time.Second, time.Second, 0},
+	}, {
+		desc: "1s < 1m",
+		test: []interface{}{time.Second, time.Minute, -1},
+	}, {
+		desc: "1m > 1s",
+		test: []interface{}{time.Minute, time.Second, 1},
+	}, {
+		desc: "-1s < 0",
+		test: []interface{}{-time.Second, time.Duration(0), -1},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.test[2], treap.DurationComparator(tc.test[0], tc.test[1]),
+				"constraint %s violated", tc.desc)
+		})
+	}
+}
+
+func TestBoolComparator(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		test []interface{}
+	}{{
+		desc: "nil < false",
+		test: []interface{}{nil, false, -1},
+	}, {
+		desc: "false > nil",
+		test: []interface{}{false, nil, 1},
+	}, {
+		desc: "false < true",
+		test: []interface{}{false, true, -1},
+	}, {
+		desc: "true > false",
+		test: []interface{}{true, false, 1},
+	}, {
+		desc: "true == true",
+		test: []interface{}{true, true, 0},
+	}, {
+		desc: "false == false",
+		test: []interface{}{false, false, 0},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.test[2], treap.BoolComparator(tc.test[0], tc.test[1]),
+				"constraint %s violated", tc.desc)
+		})
+	}
+}
+
+func TestUUIDComparator(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		test []interface{}
+	}{{
+		desc: "nil < zero",
+		test: []interface{}{nil, [16]byte{}, -1},
+	}, {
+		desc: "zero > nil",
+		test: []interface{}{[16]byte{}, nil, 1},
+	}, {
+		desc: "zero == zero",
+		test: []interface{}{[16]byte{}, [16]byte{}, 0},
+	}, {
+		desc: "zero < one",
+		test: []interface{}{[16]byte{}, [16]byte{15: 1}, -1},
+	}, {
+		desc: "high byte dominates",
+		test: []interface{}{[16]byte{0: 1}, [16]byte{15: 0xff}, 1},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.test[2], treap.UUIDComparator(tc.test[0], tc.test[1]),
+				"constraint %s violated", tc.desc)
+		})
+	}
+}
+
+func TestBigIntComparator(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		test []interface{}
+	}{{
+		desc: "nil < 1",
+		test: []interface{}{nil, big.NewInt(1), -1},
+	}, {
+		desc: "1 > nil",
+		test: []interface{}{big.NewInt(1), nil, 1},
+	}, {
+		desc: "1 == 1",
+		test: []interface{}{big.NewInt(1), big.NewInt(1), 0},
+	}, {
+		desc: "1 < 2",
+		test: []interface{}{big.NewInt(1), big.NewInt(2), -1},
+	}, {
+		desc: "2 > 1",
+		test: []interface{}{big.NewInt(2), big.NewInt(1), 1},
+	}, {
+		desc: "2^64 > 2^63",
+		test: []interface{}{new(big.Int).Lsh(big.NewInt(1), 64), new(big.Int).Lsh(big.NewInt(1), 63), 1},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.test[2], treap.BigIntComparator(tc.test[0], tc.test[1]),
+				"constraint %s violated", tc.desc)
+		})
+	}
+}
+
+func TestBigFloatComparator(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		test []interface{}
+	}{{
+		desc: "nil < 1",
+		test: []interface{}{nil, big.NewFloat(1), -1},
+	}, {
+		desc: "1 > nil",
+		test: []interface{}{big.NewFloat(1), nil, 1},
+	}, {
+		desc: "1 == 1",
+		test: []interface{}{big.NewFloat(1), big.NewFloat(1), 0},
+	}, {
+		desc: "1 < 1.5",
+		test: []interface{}{big.NewFloat(1), big.NewFloat(1.5), -1},
+	}, {
+		desc: "1.5 > 1",
+		test: []interface{}{big.NewFloat(1.5), big.NewFloat(1), 1},
+	}, {
+		desc: "-Inf < 0",
+		test: []interface{}{new(big.Float).SetInf(true), big.NewFloat(0), -1},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.test[2], treap.BigFloatComparator(tc.test[0], tc.test[1]),
+				"constraint %s violated", tc.desc)
+		})
+	}
+}
+
+func TestAddrComparator(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		test []interface{}
+	}{{
+		desc: "nil < 10.0.0.1",
+		test: []interface{}{nil, netip.MustParseAddr("10.0.0.1"), -1},
+	}, {
+		desc: "10.0.0.1 > nil",
+		test: []interface{}{netip.MustParseAddr("10.0.0.1"), nil, 1},
+	}, {
+		desc: "10.0.0.1 == 10.0.0.1",
+		test: []interface{}{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.1"), 0},
+	}, {
+		desc: "10.0.0.1 < 10.0.0.2",
+		test: []interface{}{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2"), -1},
+	}, {
+		desc: "9.255.255.255 < 10.0.0.0",
+		test: []interface{}{netip.MustParseAddr("9.255.255.255"), netip.MustParseAddr("10.0.0.0"), -1},
+	}, {
+		desc: "IPv4 < IPv6",
+		test: []interface{}{netip.MustParseAddr("255.255.255.255"), netip.MustParseAddr("::1"), -1},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.test[2], treap.AddrComparator(tc.test[0], tc.test[1]),
+				"constraint %s violated", tc.desc)
+		})
+	}
+}
+
+func TestPrefixComparator(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		test []interface{}
+	}{{
+		desc: "nil < 10.0.0.0/8",
+		test: []interface{}{nil, netip.MustParsePrefix("10.0.0.0/8"), -1},
+	}, {
+		desc: "10.0.0.0/8 > nil",
+		test: []interface{}{netip.MustParsePrefix("10.0.0.0/8"), nil, 1},
+	}, {
+		desc: "10.0.0.0/8 == 10.0.0.0/8",
+		test: []interface{}{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("10.0.0.0/8"), 0},
+	}, {
+		desc: "10.0.0.0/8 < 10.0.0.0/16",
+		test: []interface{}{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("10.0.0.0/16"), -1},
+	}, {
+		desc: "10.255.0.0/16 < 11.0.0.0/8",
+		test: []interface{}{netip.MustParsePrefix("10.255.0.0/16"), netip.MustParsePrefix("11.0.0.0/8"), -1},
+	}, {
+		desc: "10.0.0.0/24 < 10.1.0.0/16",
+		test: []interface{}{netip.MustParsePrefix("10.0.0.0/24"), netip.MustParsePrefix("10.1.0.0/16"), -1},
+	}, {
+		desc: "9.0.0.0/8 < 10.0.0.0/8",
+		test: []interface{}{netip.MustParsePrefix("9.0.0.0/8"), netip.MustParsePrefix("10.0.0.0/8"), -1},
+	}, {
+		desc: "10.0.0.0/8 < 10.1.2.3/8",
+		test: []interface{}{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("10.1.2.3/8"), -1},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.test[2], treap.PrefixComparator(tc.test[0], tc.test[1]),
+				"constraint %s violated", tc.desc)
+		})
+	}
+}
+
+func TestPrefixComparator_Containment(t *testing.T) {
+	t.Parallel()
+
+	h := treap.Handle{
+		CompareKeys:    treap.PrefixComparator,
+		CompareWeights: treap.IntComparator,
+	}
+
+	var root *treap.Node
+	for i, p := range []string{
+		"10.1.0.0/16", "192.168.0.0/16", "10.0.0.0/8", "10.1.2.0/24",
+		"11.0.0.0/8", "10.2.0.0/16", "0.0.0.0/0",
+	} {
+		root, _ = h.Insert(root, netip.MustParsePrefix(p), nil, i)
+	}
+
+	// Prefixes contained in 10.0.0.0/8 are contiguous, and directly follow it.
+	var got []string
+	for it := h.Iter(root); it.Node != nil; it.Next() {
+		got = append(got, it.Key.(netip.Prefix).String())
+	}
+
+	assert.Equal(t, []string{
+		"0.0.0.0/0",
+		"10.0.0.0/8",
+		"10.1.0.0/16",
+		"10.1.2.0/24",
+		"10.2.0.0/16",
+		"11.0.0.0/8",
+		"192.168.0.0/16",
+	}, got)
+}
+
+func TestNaturalStringComparator(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		test []interface{}
+	}{{
+		desc: "nil < a",
+		test: []interface{}{nil, "a", -1},
+	}, {
+		desc: "a > nil",
+		test: []interface{}{"a", nil, 1},
+	}, {
+		desc: "file2 < file10",
+		test: []interface{}{"file2", "file10", -1},
+	}, {
+		desc: "file10 > file2",
+		test: []interface{}{"file10", "file2", 1},
+	}, {
+		desc: "file10 == file10",
+		test: []interface{}{"file10", "file10", 0},
+	}, {
+		desc: "file1 < file01",
+		test: []interface{}{"file1", "file01", 1},
+	}, {
+		desc: "file01 < file2",
+		test: []interface{}{"file01", "file2", -1},
+	}, {
+		desc: "file < file1",
+		test: []interface{}{"file", "file1", -1},
+	}, {
+		desc: "a1b2 < a1b10",
+		test: []interface{}{"a1b2", "a1b10", -1},
+	}, {
+		desc: "file9.txt < file9a.txt",
+		test: []interface{}{"file9.txt", "file9a.txt", -1},
+	}, {
+		desc: "x99999999999999999999 < x100000000000000000000",
+		test: []interface{}{"x99999999999999999999", "x100000000000000000000", -1},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.test[2], treap.NaturalStringComparator(tc.test[0], tc.test[1]),
+				"constraint %s violated", tc.desc)
+		})
+	}
+}
+
+func TestFoldStringComparator(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		test []interface{}
+	}{{
+		desc: "nil < a",
+		test: []interface{}{nil, "a", -1},
+	}, {
+		desc: "a > nil",
+		test: []interface{}{"a", nil, 1},
+	}, {
+		desc: "apple < Zebra",
+		test: []interface{}{"apple", "Zebra", -1},
+	}, {
+		desc: "Zebra > apple",
+		test: []interface{}{"Zebra", "apple", 1},
+	}, {
+		desc: "GO == go",
+		test: []interface{}{"GO", "go", 0},
+	}, {
+		desc: "go < gopher",
+		test: []interface{}{"go", "GOPHER", -1},
+	}, {
+		desc: "ΣΑΣ == σας",
+		test: []interface{}{"ΣΑΣ", "σας", 0},
+	}, {
+		desc: "Kelvin sign == k",
+		test: []interface{}{"K", "k", 0},
+	}, {
+		desc: "long s == S",
+		test: []interface{}{"ſ", "S", 0},
+	}, {
+		desc: "dotted I != i",
+		test: []interface{}{"\u0130", "i", 1},
+	}, {
+		desc: "dotted I != I",
+		test: []interface{}{"\u0130", "I", 1},
+	}, {
+		desc: "dotless i != i",
+		test: []interface{}{"\u0131", "i", 1},
+	}, {
+		desc: "dotless i != I",
+		test: []interface{}{"\u0131", "I", 1},
+	}, {
+		desc: "dotted I < dotless i",
+		test: []interface{}{"\u0130", "\u0131", -1},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.test[2], treap.FoldStringComparator(tc.test[0], tc.test[1]),
+				"constraint %s violated", tc.desc)
+		})
+	}
+}
+
+func TestFoldStringComparator_EqualFold(t *testing.T) {
+	t.Parallel()
+
+	for r := rune(0); r <= unicode.MaxRune; r++ {
+		if !utf8.ValidRune(r) {
+			continue
+		}
+
+		for _, other := range []rune{unicode.ToLower(r), unicode.ToUpper(r), unicode.ToTitle(r)} {
+			a, b := string(r), string(other)
+			if (treap.FoldStringComparator(a, b) == 0) != strings.EqualFold(a, b) {
+				t.Fatalf("%q and %q: comparator disagrees with strings.EqualFold", a, b)
+			}
+		}
+	}
+}
+
+func TestSemverComparator(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		test []interface{}
+	}{{
+		desc: "nil < 1.0.0",
+		test: []interface{}{nil, "1.0.0", -1},
+	}, {
+		desc: "1.0.0 > nil",
+		test: []interface{}{"1.0.0", nil, 1},
+	}, {
+		desc: "1.0.0 == 1.0.0",
+		test: []interface{}{"1.0.0", "1.0.0", 0},
+	}, {
+		desc: "1.2.0 < 1.10.0",
+		test: []interface{}{"1.2.0", "1.10.0", -1},
+	}, {
+		desc: "1.0.0-alpha < 1.0.0",
+		test: []interface{}{"1.0.0-alpha", "1.0.0", -1},
+	}, {
+		desc: "1.0.0-alpha < 1.0.0-alpha.1",
+		test: []interface{}{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+	}, {
+		desc: "1.0.0-alpha.1 < 1.0.0-alpha.beta",
+		test: []interface{}{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+	}, {
+		desc: "1.0.0-beta.2 < 1.0.0-beta.11",
+		test: []interface{}{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+	}, {
+		desc: "1.0.0-rc.1 > 1.0.0-beta.11",
+		test: []interface{}{"1.0.0-rc.1", "1.0.0-beta.11", 1},
+	}, {
+		desc: "v2.0.0 > 1.9.9",
+		test: []interface{}{"v2.0.0", "1.9.9", 1},
+	}, {
+		desc: "1.0.0+build.1 < 1.0.0+build.2",
+		test: []interface{}{"1.0.0+build.1", "1.0.0+build.2", -1},
+	}, {
+		desc: "1.0.0+build.2 < 1.0.1",
+		test: []interface{}{"1.0.0+build.2", "1.0.1", -1},
+	}, {
+		desc: "invalid < 0.0.0",
+		test: []interface{}{"not-a-version", "0.0.0", -1},
+	}, {
+		desc: "leading zero is invalid",
+		test: []interface{}{"01.0.0", "0.0.0", -1},
+	}, {
+		desc: "trailing dot is invalid",
+		test: []interface{}{"1.0.0-alpha.", "0.0.0", -1},
+	}, {
+		desc: "missing patch is invalid",
+		test: []interface{}{"1.0", "0.0.0", -1},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.test[2], treap.SemverComparator(tc.test[0], tc.test[1]),
+				"constraint %s violated", tc.desc)
+		})
+	}
+}
+
+func TestSemverComparator_Precedence(t *testing.T) {
+	t.Parallel()
+
+	// Example from https://semver.org/#spec-item-11
+	want := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+	}
+
+	h := treap.Handle{
+		CompareKeys:    treap.SemverComparator,
+		CompareWeights: treap.IntComparator,
+	}
+
+	var root *treap.Node
+	for i := range want {
+		root, _ = h.Insert(root, want[len(want)-i-1], nil, i)
+	}
+
+	var got []string
+	for it := h.Iter(root); it.Node != nil; it.Next() {
+		got = append(got, it.Key.(string))
+	}
+
+	assert.Equal(t, want, got)
+}
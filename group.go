@@ -0,0 +1,131 @@
+package treap
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// Group is a set of treaps that are updated together, atomically.  Readers always
+// observe a consistent snapshot of every treap in the group, and never block.
+//
+// The roots are stored behind a single pointer to an immutable slice, which is
+// replaced using a CAS loop.  Concurrent updates to a Group therefore conflict even if
+// they touch different treaps.
+type Group struct {
+	hs  []Handle
+	ptr unsafe.Pointer // *[]*Node
+}
+
+// NewGroup returns an empty group containing one treap for each handle.  Treaps are
+// identified by the index of their handle.
+func NewGroup(hs ...Handle) *Group {
+	roots := make([]*Node, len(hs))
+	return &Group{
+		hs:  hs,
+		ptr: unsafe.Pointer(&roots),
+	}
+}
+
+// Snapshot returns the current roots of the treaps in the group.  The returned slice
+// MUST NOT be modified.
+func (g *Group) Snapshot() []*Node {
+	return *(*[]*Node)(atomic.LoadPointer(&g.ptr))
+}
+
+// Update applies f to a consistent snapshot of the group, and atomically commits the
+// resulting roots.  If another update was committed in the meantime, f is called
+// again on a fresh snapshot.  As such, f SHOULD NOT have side-effects.
+//
+// If f returns an error, the transaction is aborted and the error is returned.
+func (g *Group) Update(f func(*GroupTxn) error) error {
+	for {
+		old := atomic.LoadPointer(&g.ptr)
+
+		tx := &GroupTxn{
+			hs:    g.hs,
+			roots: append([]*Node(nil), *(*[]*Node)(old)...),
+		}
+
+		if err := f(tx); err != nil {
+			return err
+		}
+
+		if !tx.dirty || atomic.CompareAndSwapPointer(&g.ptr, old, unsafe.Pointer(&tx.roots)) {
+			return nil
+		}
+	}
+}
+
+// GroupTxn is a transaction over the treaps in a Group.  Each of its methods applies
+// the corresponding Handle method to the ith treap.  A GroupTxn is NOT thread-safe,
+// and MUST NOT be used after the function passed to Group.Update has returned.
+type GroupTxn struct {
+	hs    []Handle
+	roots []*Node
+	dirty bool
+}
+
+// Root returns the current root of the ith treap, including any modifications made
+// by the transaction.
+func (tx *GroupTxn) Root(i int) *Node {
+	return tx.roots[i]
+}
+
+// SetRoot replaces the root of the ith treap.  The new root MUST have been derived
+// using the ith treap's handle.
+func (tx *GroupTxn) SetRoot(i int, n *Node) {
+	tx.roots[i] = n
+	tx.dirty = true
+}
+
+// Get an element by key from the ith treap.
+func (tx *GroupTxn) Get(i int, key interface{}) (interface{}, bool) {
+	return tx.hs[i].Get(tx.roots[i], key)
+}
+
+// Insert an element into the ith treap, returning false if it is already present.
+func (tx *GroupTxn) Insert(i int, key, val, weight interface{}) (ok bool) {
+	var root *Node
+	if root, ok = tx.hs[i].Insert(tx.roots[i], key, val, weight); ok {
+		tx.SetRoot(i, root)
+	}
+
+	return
+}
+
+// Upsert updates an element in the ith treap, creating one if it is missing.
+func (tx *GroupTxn) Upsert(i int, key, val, weight interface{}) (created bool) {
+	var root *Node
+	root, created = tx.hs[i].Upsert(tx.roots[i], key, val, weight)
+	tx.SetRoot(i, root)
+	return
+}
+
+// SetWeight adjusts the weight of an element in the ith treap, returning false if it
+// is not present.
+func (tx *GroupTxn) SetWeight(i int, key, weight interface{}) (ok bool) {
+	var root *Node
+	if root, ok = tx.hs[i].SetWeight(tx.roots[i], key, weight); ok {
+		tx.SetRoot(i, root)
+	}
+
+	return
+}
+
+// Delete an element from the ith treap.
+func (tx *GroupTxn) Delete(i int, key interface{}) {
+	tx.SetRoot(i, tx.hs[i].Delete(tx.roots[i], key))
+}
+
+// Pop the element with the lowest weight from the ith treap.  The returned node is the
+// former root of the treap; its Left and Right fields SHOULD be ignored.  Returns nil
+// if the treap is empty.
+func (tx *GroupTxn) Pop(i int) *Node {
+	root := tx.roots[i]
+	if root != nil {
+		_, tail := tx.hs[i].Pop(root)
+		tx.SetRoot(i, tail)
+	}
+
+	return root
+}
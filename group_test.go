@@ -0,0 +1,87 @@
+package treap_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	primary = iota
+	expiry
+)
+
+func TestGroup(t *testing.T) {
+	t.Parallel()
+
+	g := treap.NewGroup(handle, handle)
+	assert.Equal(t, []*treap.Node{nil, nil}, g.Snapshot())
+
+	t.Run("Commit", func(t *testing.T) {
+		err := g.Update(func(tx *treap.GroupTxn) error {
+			require.True(t, tx.Insert(primary, 1, "a", 10))
+			require.True(t, tx.Insert(expiry, 10, 1, 10))
+			require.True(t, tx.Insert(primary, 2, "b", 5))
+			require.True(t, tx.Insert(expiry, 5, 2, 5))
+			return nil
+		})
+		require.NoError(t, err)
+
+		roots := g.Snapshot()
+		v, ok := handle.Get(roots[primary], 1)
+		assert.True(t, ok)
+		assert.Equal(t, "a", v)
+
+		v, ok = handle.Get(roots[expiry], 10)
+		assert.True(t, ok)
+		assert.Equal(t, 1, v)
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		before := g.Snapshot()
+
+		errAbort := errors.New("abort")
+		err := g.Update(func(tx *treap.GroupTxn) error {
+			tx.Delete(primary, 1)
+			tx.Delete(expiry, 10)
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+		assert.Equal(t, before, g.Snapshot(), "aborted update should not be committed")
+	})
+
+	t.Run("Pop", func(t *testing.T) {
+		err := g.Update(func(tx *treap.GroupTxn) error {
+			n := tx.Pop(expiry)
+			require.NotNil(t, n)
+			assert.Equal(t, 5, n.Key)
+
+			tx.Delete(primary, n.Value)
+			return nil
+		})
+		require.NoError(t, err)
+
+		roots := g.Snapshot()
+		_, ok := handle.Get(roots[primary], 2)
+		assert.False(t, ok)
+		_, ok = handle.Get(roots[expiry], 5)
+		assert.False(t, ok)
+	})
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		before := g.Snapshot()
+
+		err := g.Update(func(tx *treap.GroupTxn) error {
+			_, ok := tx.Get(primary, 1)
+			assert.True(t, ok)
+			return nil
+		})
+		require.NoError(t, err)
+
+		after := g.Snapshot()
+		assert.Same(t, &before[0], &after[0], "read-only update should not commit")
+	})
+}
@@ -120,6 +120,10 @@ func (h Handle) upsert(n *Node, k, v, w interface{}, create, update bool, fn fun
 		if create { // not SetWeight
 			res.Value = v // upsert; set new value.
 		}
+
+		// the weight may have increased, in which case the node must sink
+		res = h.sink(res)
+		return
 	}
 
 	if res.Left != nil && h.CompareWeights(res.Left.Weight, res.Weight) < 0 {
@@ -135,10 +139,37 @@ func (h Handle) upsert(n *Node, k, v, w interface{}, create, update bool, fn fun
 // present in the treap.  If it is, it WILL NOT be present in either of the resulting
 // subtreaps.
 //
+// Split does not depend on CompareWeights, so it is safe to use with any convention for
+// nil weights (see NilFirst, NilLast and NilRejected).
+//
 // O(log n) if the treap is balanced (see Get).
 func (h Handle) Split(n *Node, key interface{}) (*Node, *Node) {
-	ins, _ := h.Upsert(n, key, nil, nil)
-	return ins.Left, ins.Right
+	if n == nil {
+		return nil, nil
+	}
+
+	switch comp := h.CompareKeys(key, n.Key); {
+	case comp < 0:
+		left, right := h.Split(n.Left, key)
+		return left, &Node{
+			Key:    n.Key,
+			Value:  n.Value,
+			Weight: n.Weight,
+			Left:   right,
+			Right:  n.Right,
+		}
+	case comp > 0:
+		left, right := h.Split(n.Right, key)
+		return &Node{
+			Key:    n.Key,
+			Value:  n.Value,
+			Weight: n.Weight,
+			Left:   n.Left,
+			Right:  left,
+		}, right
+	default:
+		return n.Left, n.Right
+	}
 }
 
 // Merge two treaps.  The root will be the root of the input treap with the lowest
@@ -193,6 +224,34 @@ func (h Handle) Pop(n *Node) (interface{}, *Node) {
 	return n.Value, h.Merge(n.Left, n.Right)
 }
 
+// PopWhile pops values off the heap for as long as f returns true, and returns the
+// remaining treap.  The node passed to f is the current root, and is guaranteed to be
+// non-nil.  This is useful for draining items whose weights have crossed a threshold,
+// e.g. expired deadlines.
+//
+// O(k log n), where k is the number of popped values.
+func (h Handle) PopWhile(n *Node, f func(*Node) bool) *Node {
+	for n != nil && f(n) {
+		n = h.Merge(n.Left, n.Right)
+	}
+
+	return n
+}
+
+// sink n until neither of its children has a lower weight.
+func (h Handle) sink(n *Node) *Node {
+	if n.Left != nil && h.CompareWeights(n.Left.Weight, n.Weight) < 0 &&
+		(n.Right == nil || h.CompareWeights(n.Left.Weight, n.Right.Weight) <= 0) {
+		n = h.leftRotation(n)
+		n.Right = h.sink(n.Right)
+	} else if n.Right != nil && h.CompareWeights(n.Right.Weight, n.Weight) < 0 {
+		n = h.rightRotation(n)
+		n.Left = h.sink(n.Left)
+	}
+
+	return n
+}
+
 func (h Handle) leftRotation(n *Node) *Node {
 	return &Node{
 		Key:    n.Left.Key,
@@ -7,6 +7,7 @@ import "sync"
 type Iterator struct {
 	*Node
 	stack *stack
+	while func(key interface{}) bool // optional; iteration stops when it returns false
 }
 
 // Iter walks the tree in key-order.
@@ -17,6 +18,39 @@ func (h Handle) Iter(n *Node) *Iterator {
 	return it
 }
 
+// iterFrom returns an iterator that walks the tree in key-order, starting from the
+// first key that is greater than or equal to key.
+func (h Handle) iterFrom(n *Node, key interface{}) *Iterator {
+	it := iterPool.Get().(*Iterator)
+	for n != nil {
+		if h.CompareKeys(n.Key, key) >= 0 {
+			it.stack = push(it.stack, n)
+			n = n.Left
+		} else {
+			n = n.Right
+		}
+	}
+
+	it.Node, it.stack = pop(it.stack)
+	return it
+}
+
+// PrefixIter walks the tree in key-order, visiting only the keys that begin with
+// prefix.  Keys MUST be either strings or []byte, and prefix MUST have the same type.
+// PrefixIter assumes that keys are ordered bytewise, as per StringComparator and
+// BytesComparator.
+//
+// O(log n + m), where m is the number of keys visited.
+func (h Handle) PrefixIter(n *Node, prefix interface{}) *Iterator {
+	it := h.iterFrom(n, prefix)
+	it.while = func(key interface{}) bool {
+		return hasPrefix(key, prefix)
+	}
+
+	it.check()
+	return it
+}
+
 // Next item.
 func (it *Iterator) Next() {
 	// are we resuming?
@@ -35,6 +69,19 @@ func (it *Iterator) Next() {
 		it.stack = push(it.stack, it.Node)
 		it.Node = it.Node.Left
 	}
+
+	it.check()
+}
+
+// check stops the iteration if the current key fails the iterator's bound.
+func (it *Iterator) check() {
+	if it.Node != nil && it.while != nil && !it.while(it.Key) {
+		for it.stack != nil {
+			_, it.stack = pop(it.stack)
+		}
+
+		it.Node = nil
+	}
 }
 
 // Finish SHOULD be called when the iterator has been
@@ -46,6 +93,8 @@ func (it *Iterator) Finish() {
 		_, it.stack = pop(it.stack)
 	}
 
+	it.Node = nil
+	it.while = nil
 	iterPool.Put(it)
 }
 
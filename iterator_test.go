@@ -102,3 +102,55 @@ func TestIter_MultiEntry(t *testing.T) {
 		assert.Equal(t, n.Key, ns[i].Key, "iterator should traverse in key order")
 	}
 }
+
+func TestPrefixIter(t *testing.T) {
+	t.Parallel()
+
+	words := []string{"car", "card", "care", "careful", "cart", "cat", "ca", "dog", "c"}
+
+	t.Run("String", func(t *testing.T) {
+		h := treap.Handle{
+			CompareKeys:    treap.StringComparator,
+			CompareWeights: treap.IntComparator,
+		}
+
+		var root *treap.Node
+		for i, w := range words {
+			root, _ = h.Insert(root, w, nil, i)
+		}
+
+		collect := func(prefix string) (ks []string) {
+			for it := h.PrefixIter(root, prefix); it.Node != nil; it.Next() {
+				ks = append(ks, it.Key.(string))
+			}
+			return
+		}
+
+		assert.Equal(t, []string{"car", "card", "care", "careful", "cart"}, collect("car"))
+		assert.Equal(t, []string{"care", "careful"}, collect("care"))
+		assert.Equal(t, []string{"dog"}, collect("d"))
+		assert.Empty(t, collect("x"))
+		assert.Len(t, collect(""), len(words))
+	})
+
+	t.Run("Bytes", func(t *testing.T) {
+		h := treap.Handle{
+			CompareKeys:    treap.BytesComparator,
+			CompareWeights: treap.IntComparator,
+		}
+
+		var root *treap.Node
+		for i, k := range [][]byte{{0x01}, {0xff}, {0xff, 0x00}, {0xff, 0xff}} {
+			root, _ = h.Insert(root, k, nil, i)
+		}
+
+		var ks [][]byte
+		it := h.PrefixIter(root, []byte{0xff})
+		for ; it.Node != nil; it.Next() {
+			ks = append(ks, it.Key.([]byte))
+		}
+		it.Finish()
+
+		assert.Equal(t, [][]byte{{0xff}, {0xff, 0x00}, {0xff, 0xff}}, ks)
+	})
+}
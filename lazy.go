@@ -0,0 +1,343 @@
+package treap
+
+// LazyNode is the recursive datastructure that defines a persistent treap whose nodes
+// can be updated in bulk, in O(log n).  See LazyHandle.UpdateRange.
+//
+// A node's Key, Value and Weight are always up-to-date.  Its children, however, may
+// have an update pending, which is why they are unexported.  Use Left and Right to
+// read them with pending updates applied.
+//
+// The zero value is ready to use.
+type LazyNode struct {
+	Weight, Key, Value interface{}
+	left, right        *LazyNode
+	pending            Update // applied to the node, but not yet to its children
+}
+
+// Left returns the left subtree, with any pending update applied.  This allocates a
+// copy of the child if an update is pending.
+func (n *LazyNode) Left() *LazyNode {
+	return apply(n.left, n.pending)
+}
+
+// Right returns the right subtree, with any pending update applied.  This allocates a
+// copy of the child if an update is pending.
+func (n *LazyNode) Right() *LazyNode {
+	return apply(n.right, n.pending)
+}
+
+// LazyHandle performs purely functional transformations on a treap of LazyNodes.  In
+// addition to the usual operations, it can apply an Update to every node in a key
+// range in O(log n).
+//
+// Pending updates are pushed down to a node's children only when the node is copied
+// by a write.  Reads never copy nodes;  they apply pending updates to the values and
+// weights they return instead.  A Handle can be converted to a LazyHandle, and vice
+// versa.
+type LazyHandle Handle
+
+// Get an element by key.  Returns false if the key is not in the treap.
+//
+// O(log n) if the treap is balanced (see Handle.Get).
+func (h LazyHandle) Get(n *LazyNode, key interface{}) (interface{}, bool) {
+	value, _, ok := h.lookup(n, key)
+	return value, ok
+}
+
+// GetWeight returns the weight of an element.  Returns false if the key is not in the
+// treap.
+//
+// O(log n) if the treap is balanced (see Handle.Get).
+func (h LazyHandle) GetWeight(n *LazyNode, key interface{}) (interface{}, bool) {
+	_, weight, ok := h.lookup(n, key)
+	return weight, ok
+}
+
+// lookup finds key without copying nodes, and applies the updates pending on the path
+// to the node's value and weight.
+func (h LazyHandle) lookup(n *LazyNode, key interface{}) (value, weight interface{}, ok bool) {
+	var (
+		buf     [32]Update
+		pending = buf[:0] // pending updates along the path, from the root down
+	)
+
+	for n != nil {
+		comp := h.CompareKeys(key, n.Key)
+		if comp == 0 {
+			value, weight = n.Value, n.Weight
+
+			// Updates that are deeper in the treap are older, so they apply first.
+			for i := len(pending) - 1; i >= 0; i-- {
+				value, weight = pending[i].Apply(value, weight)
+			}
+
+			return value, weight, true
+		}
+
+		if n.pending != nil {
+			pending = append(pending, n.pending)
+		}
+
+		if comp < 0 {
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+
+	return nil, nil, false
+}
+
+// Insert an element into the treap, returning false if the element is already present.
+//
+// O(log n) if the treap is balanced (see Handle.Get).
+func (h LazyHandle) Insert(n *LazyNode, key, val, weight interface{}) (*LazyNode, bool) {
+	if _, _, found := h.lookup(n, key); found {
+		return n, false
+	}
+
+	return h.insert(n, key, val, weight), true
+}
+
+// Upsert updates an element, creating one if it is missing.
+//
+// O(log n) if the treap is balanced (see Handle.Get).
+func (h LazyHandle) Upsert(n *LazyNode, key, val, weight interface{}) (*LazyNode, bool) {
+	_, _, found := h.lookup(n, key)
+	if found {
+		n = h.Delete(n, key)
+	}
+
+	return h.insert(n, key, val, weight), !found
+}
+
+// SetWeight adjusts the weight of the specified item.  It is a nop if the key is not in
+// the treap, in which case the returned bool is false.
+//
+// O(log n) if the treap is balanced (see Handle.Get).
+func (h LazyHandle) SetWeight(n *LazyNode, key, weight interface{}) (*LazyNode, bool) {
+	value, _, found := h.lookup(n, key)
+	if !found {
+		return n, false
+	}
+
+	return h.insert(h.Delete(n, key), key, value, weight), true
+}
+
+// Delete a value.
+//
+// O(log n) if the treap is balanced (see Handle.Get).
+func (h LazyHandle) Delete(n *LazyNode, key interface{}) *LazyNode {
+	if n == nil {
+		return nil
+	}
+
+	left, right := n.Left(), n.Right()
+
+	switch comp := h.CompareKeys(key, n.Key); {
+	case comp < 0:
+		if left = h.Delete(left, key); left == n.left && n.pending == nil {
+			return n // not found
+		}
+	case comp > 0:
+		if right = h.Delete(right, key); right == n.right && n.pending == nil {
+			return n // not found
+		}
+	default:
+		return h.merge(left, right)
+	}
+
+	return &LazyNode{Key: n.Key, Value: n.Value, Weight: n.Weight, left: left, right: right}
+}
+
+// Pop the next value off the heap.  By default, this is the item with the lowest
+// weight.
+//
+// O(log n)
+func (h LazyHandle) Pop(n *LazyNode) (interface{}, *LazyNode) {
+	if n == nil {
+		return nil, nil
+	}
+
+	return n.Value, h.merge(n.Left(), n.Right())
+}
+
+// UpdateRange applies u to every node in the range between lo and hi.  The endpoints
+// are included or excluded as per b.
+//
+// The update is applied to the root of the range, and recorded as pending for its
+// descendants.  Treaps that share nodes with n are unaffected.
+//
+// UpdateRange performs two splits and two merges, so it is O(log n) if the treap is
+// balanced (see Handle.Get), regardless of the number of keys in the range.
+func (h LazyHandle) UpdateRange(n *LazyNode, lo, hi interface{}, b Bounds, u Update) *LazyNode {
+	left, right := h.split(n, lo, !b.includesLo())
+	mid, right := h.split(right, hi, b.includesHi())
+	return h.merge(h.merge(left, apply(mid, u)), right)
+}
+
+// UpdateAll applies u to every node in the treap.  This is useful for aging every
+// weight at once, e.g. with Offset or Scale.  Treaps that share nodes with n are
+// unaffected.
+//
+// O(1)
+func (h LazyHandle) UpdateAll(n *LazyNode, u Update) *LazyNode {
+	return apply(n, u)
+}
+
+// insert a key that is not already present.  The key is inserted at the first node on
+// its search path that is heavier than it, and that node's subtree is split around it.
+func (h LazyHandle) insert(n *LazyNode, key, val, weight interface{}) *LazyNode {
+	if n == nil || h.CompareWeights(weight, n.Weight) < 0 {
+		left, right := h.split(n, key, false)
+		return &LazyNode{Key: key, Value: val, Weight: weight, left: left, right: right}
+	}
+
+	left, right := n.Left(), n.Right()
+	if h.CompareKeys(key, n.Key) < 0 {
+		left = h.insert(left, key, val, weight)
+	} else {
+		right = h.insert(right, key, val, weight)
+	}
+
+	return &LazyNode{Key: n.Key, Value: n.Value, Weight: n.Weight, left: left, right: right}
+}
+
+// split a treap at key.  A node whose key is equal to key is placed in the left treap
+// if keyLeft is true, and in the right treap otherwise.
+func (h LazyHandle) split(n *LazyNode, key interface{}, keyLeft bool) (*LazyNode, *LazyNode) {
+	if n == nil {
+		return nil, nil
+	}
+
+	left, right := n.Left(), n.Right()
+
+	if comp := h.CompareKeys(key, n.Key); comp < 0 || (comp == 0 && !keyLeft) {
+		l, r := h.split(left, key, keyLeft)
+		return l, &LazyNode{Key: n.Key, Value: n.Value, Weight: n.Weight, left: r, right: right}
+	}
+
+	l, r := h.split(right, key, keyLeft)
+	return &LazyNode{Key: n.Key, Value: n.Value, Weight: n.Weight, left: left, right: l}, r
+}
+
+// merge two treaps, where every key in left is less than every key in right.
+func (h LazyHandle) merge(left, right *LazyNode) *LazyNode {
+	switch {
+	case left == nil:
+		return right
+	case right == nil:
+		return left
+	case h.CompareWeights(left.Weight, right.Weight) < 0:
+		return &LazyNode{
+			Key:    left.Key,
+			Value:  left.Value,
+			Weight: left.Weight,
+			left:   left.Left(),
+			right:  h.merge(left.Right(), right),
+		}
+	default:
+		return &LazyNode{
+			Key:    right.Key,
+			Value:  right.Value,
+			Weight: right.Weight,
+			left:   h.merge(left, right.Left()),
+			right:  right.Right(),
+		}
+	}
+}
+
+// apply u to the subtree rooted at n, returning a copy of n.  The update is applied to
+// n immediately, and is pending for its descendants.
+func apply(n *LazyNode, u Update) *LazyNode {
+	if n == nil || u == nil {
+		return n
+	}
+
+	pending := u
+	if n.pending != nil {
+		pending = n.pending.Compose(u)
+	}
+
+	value, weight := u.Apply(n.Value, n.Weight)
+	return &LazyNode{
+		Key:     n.Key,
+		Value:   value,
+		Weight:  weight,
+		left:    n.left,
+		right:   n.right,
+		pending: pending,
+	}
+}
+
+// Iter walks the treap in key-order.  No nodes are copied;  pending updates are
+// applied to the values and weights as they are visited.
+func (h LazyHandle) Iter(n *LazyNode) *LazyIterator {
+	it := &LazyIterator{}
+	it.descend(n, nil)
+	it.Next()
+	return it
+}
+
+// LazyIterator contains the iteration state for a treap of LazyNodes.  Its methods are
+// NOT thread-safe, but multiple concurrent iterators are supported.
+type LazyIterator struct {
+	Key, Value, Weight interface{}
+
+	valid bool
+	cur   lazyFrame
+	stack []lazyFrame
+}
+
+// lazyFrame is a node, along with the updates that are pending on its ancestors,
+// composed from the deepest ancestor to the root.
+type lazyFrame struct {
+	n       *LazyNode
+	pending Update
+}
+
+// Valid returns false once the iterator is exhausted.
+func (it *LazyIterator) Valid() bool {
+	return it.valid
+}
+
+// Next item.
+func (it *LazyIterator) Next() {
+	if it.valid {
+		it.descend(it.cur.n.right, compose(it.cur.n.pending, it.cur.pending))
+	}
+
+	if it.valid = len(it.stack) != 0; !it.valid {
+		it.Key, it.Value, it.Weight = nil, nil, nil
+		return
+	}
+
+	it.cur = it.stack[len(it.stack)-1]
+	it.stack = it.stack[:len(it.stack)-1]
+
+	it.Key, it.Value, it.Weight = it.cur.n.Key, it.cur.n.Value, it.cur.n.Weight
+	if it.cur.pending != nil {
+		it.Value, it.Weight = it.cur.pending.Apply(it.Value, it.Weight)
+	}
+}
+
+// descend pushes n and its chain of left descendants onto the stack.
+func (it *LazyIterator) descend(n *LazyNode, pending Update) {
+	for ; n != nil; n = n.left {
+		it.stack = append(it.stack, lazyFrame{n: n, pending: pending})
+		pending = compose(n.pending, pending)
+	}
+}
+
+// compose returns an update equivalent to applying first, then next.  Either may be
+// nil.
+func compose(first, next Update) Update {
+	switch {
+	case first == nil:
+		return next
+	case next == nil:
+		return first
+	default:
+		return first.Compose(next)
+	}
+}
@@ -0,0 +1,245 @@
+package treap
+
+import "math"
+
+// Multimap is a persistent treap in which a key may be associated with several values,
+// each with its own weight.  Entries that share a key are ordered by insertion.
+//
+// Internally, each entry is keyed by a MultiKey, which pairs the caller's key with a
+// sequence number.  The nodes in the treap returned by Root therefore have MultiKey
+// keys, and can be read using the Handle returned by Handle.
+//
+// Multimap is an immutable value; its methods return a new Multimap and leave the
+// receiver unchanged.  The zero value is NOT ready to use; see NewMultimap.
+type Multimap struct {
+	h    Handle
+	root *Node
+	len  int
+	seq  uint64
+}
+
+// MultiKey is the key of an entry in a Multimap.  Seq is assigned on insertion, and
+// increases monotonically.
+type MultiKey struct {
+	Key interface{}
+	Seq uint64
+}
+
+// NewMultimap returns an empty multimap whose keys are ordered by h.CompareKeys, and
+// whose weights are ordered by h.CompareWeights.
+func NewMultimap(h Handle) Multimap {
+	return Multimap{h: Handle{
+		CompareWeights: h.CompareWeights,
+		CompareKeys: func(a, b interface{}) int {
+			ka, kb := a.(MultiKey), b.(MultiKey)
+			if c := h.CompareKeys(ka.Key, kb.Key); c != 0 {
+				return c
+			}
+
+			return UInt64Comparator(ka.Seq, kb.Seq)
+		},
+	}}
+}
+
+// Handle returns the handle of the underlying treap, whose keys are MultiKeys.
+func (m Multimap) Handle() Handle { return m.h }
+
+// Root of the underlying treap, which holds the entry with the lowest weight.
+func (m Multimap) Root() *Node { return m.root }
+
+// Len returns the total number of entries.
+func (m Multimap) Len() int { return m.len }
+
+// Iter walks the multimap in key-order.  Entries that share a key are visited in
+// insertion order.
+func (m Multimap) Iter() *Iterator {
+	return m.h.Iter(m.root)
+}
+
+// Insert a value.  Existing values for the key are retained.  Returns the key of the
+// new entry, which can be passed to Delete.
+//
+// O(log n)
+func (m Multimap) Insert(key, val, weight interface{}) (Multimap, MultiKey) {
+	k := MultiKey{Key: key, Seq: m.seq}
+	m.root, _ = m.h.Insert(m.root, k, val, weight)
+	m.len++
+	m.seq++
+	return m, k
+}
+
+// GetAll returns the entries for the specified key, in insertion order.
+//
+// O(log n + m), where m is the number of entries returned.
+func (m Multimap) GetAll(key interface{}) []*Node {
+	var ns []*Node
+
+	it := m.iterKey(key)
+	defer it.Finish()
+
+	for ; it.Node != nil; it.Next() {
+		ns = append(ns, it.Node)
+	}
+
+	return ns
+}
+
+// GetAllByWeight returns the entries for the specified key, in increasing order of
+// weight.  Ties are broken arbitrarily.
+//
+// O((log n + m) log m), where m is the number of entries returned.
+func (m Multimap) GetAllByWeight(key interface{}) []*Node {
+	lo, hi := keyRange(key)
+	return m.h.topK(m.root, math.MaxInt, func(k interface{}) int {
+		switch {
+		case m.h.CompareKeys(k, lo) < 0:
+			return -1
+		case m.h.CompareKeys(k, hi) > 0:
+			return 1
+		default:
+			return 0
+		}
+	})
+}
+
+// Count returns the number of entries for the specified key.
+//
+// O(log n + m), where m is the number of entries for the key.
+func (m Multimap) Count(key interface{}) (n int) {
+	it := m.iterKey(key)
+	defer it.Finish()
+
+	for ; it.Node != nil; it.Next() {
+		n++
+	}
+
+	return
+}
+
+// Delete the specified entry.  Returns false if it was not present.
+//
+// O(log n)
+func (m Multimap) Delete(k MultiKey) (Multimap, bool) {
+	if _, ok := m.h.GetNode(m.root, k); !ok {
+		return m, false
+	}
+
+	m.root = m.h.Delete(m.root, k)
+	m.len--
+	return m, true
+}
+
+// DeleteOne deletes the oldest entry for the specified key.  Returns false if the key
+// is absent.
+//
+// O(log n)
+func (m Multimap) DeleteOne(key interface{}) (Multimap, bool) {
+	it := m.iterKey(key)
+	defer it.Finish()
+
+	if it.Node == nil {
+		return m, false
+	}
+
+	return m.Delete(it.Key.(MultiKey))
+}
+
+// DeleteAll deletes every entry for the specified key, and returns the number of
+// entries that were deleted.
+//
+// O(log n + m), where m is the number of entries deleted.
+func (m Multimap) DeleteAll(key interface{}) (Multimap, int) {
+	lo, hi := keyRange(key)
+
+	var deleted *Node
+	m.root, deleted = m.h.ExtractRange(m.root, lo, hi, Closed)
+
+	it := m.h.Iter(deleted)
+	defer it.Finish()
+
+	var n int
+	for ; it.Node != nil; it.Next() {
+		n++
+	}
+
+	m.len -= n
+	return m, n
+}
+
+// iterKey returns an iterator over the entries for the specified key.
+func (m Multimap) iterKey(key interface{}) *Iterator {
+	lo, hi := keyRange(key)
+
+	it := m.h.iterFrom(m.root, lo)
+	it.while = func(k interface{}) bool {
+		return m.h.CompareKeys(k, hi) <= 0
+	}
+
+	it.check()
+	return it
+}
+
+// keyRange returns the smallest and largest MultiKeys for the specified key.
+func keyRange(key interface{}) (lo, hi MultiKey) {
+	return MultiKey{Key: key}, MultiKey{Key: key, Seq: math.MaxUint64}
+}
+
+// Multiset is a persistent treap in which a key may occur several times, each with its
+// own weight.  It is a Multimap whose values are nil.
+//
+// Multiset is an immutable value; its methods return a new Multiset and leave the
+// receiver unchanged.  The zero value is NOT ready to use; see NewMultiset.
+type Multiset struct {
+	m Multimap
+}
+
+// NewMultiset returns an empty multiset whose keys are ordered by h.CompareKeys, and
+// whose weights are ordered by h.CompareWeights.
+func NewMultiset(h Handle) Multiset {
+	return Multiset{m: NewMultimap(h)}
+}
+
+// Handle returns the handle of the underlying treap, whose keys are MultiKeys.
+func (s Multiset) Handle() Handle { return s.m.Handle() }
+
+// Root of the underlying treap, which holds the occurrence with the lowest weight.
+func (s Multiset) Root() *Node { return s.m.Root() }
+
+// Len returns the total number of occurrences.
+func (s Multiset) Len() int { return s.m.Len() }
+
+// Iter walks the multiset in key-order.  Occurrences of a key are visited in
+// insertion order.
+func (s Multiset) Iter() *Iterator { return s.m.Iter() }
+
+// Add an occurrence of key.
+//
+// O(log n)
+func (s Multiset) Add(key, weight interface{}) Multiset {
+	s.m, _ = s.m.Insert(key, nil, weight)
+	return s
+}
+
+// Count returns the number of occurrences of key.
+//
+// O(log n + m), where m is the number of occurrences.
+func (s Multiset) Count(key interface{}) int { return s.m.Count(key) }
+
+// RemoveOne removes the oldest occurrence of key.  Returns false if the key is absent.
+//
+// O(log n)
+func (s Multiset) RemoveOne(key interface{}) (Multiset, bool) {
+	var ok bool
+	s.m, ok = s.m.DeleteOne(key)
+	return s, ok
+}
+
+// RemoveAll removes every occurrence of key, and returns the number of occurrences
+// that were removed.
+//
+// O(log n + m), where m is the number of occurrences removed.
+func (s Multiset) RemoveAll(key interface{}) (Multiset, int) {
+	var n int
+	s.m, n = s.m.DeleteAll(key)
+	return s, n
+}
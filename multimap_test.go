@@ -0,0 +1,123 @@
+package treap_test
+
+import (
+	"testing"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultimap(t *testing.T) {
+	t.Parallel()
+
+	m := treap.NewMultimap(handle)
+	for _, e := range []struct{ key, weight int }{
+		{2, 30},
+		{1, 10},
+		{2, 10},
+		{3, 5},
+		{2, 20},
+	} {
+		m, _ = m.Insert(e.key, e.weight, e.weight)
+	}
+	require.Equal(t, 5, m.Len())
+
+	values := func(ns []*treap.Node) (vs []int) {
+		for _, n := range ns {
+			vs = append(vs, n.Value.(int))
+		}
+		return
+	}
+
+	t.Run("GetAll", func(t *testing.T) {
+		assert.Equal(t, []int{30, 10, 20}, values(m.GetAll(2)), "should be in insertion order")
+		assert.Equal(t, []int{10, 20, 30}, values(m.GetAllByWeight(2)), "should be in weight order")
+		assert.Empty(t, m.GetAll(4))
+		assert.Empty(t, m.GetAllByWeight(4))
+	})
+
+	t.Run("Count", func(t *testing.T) {
+		assert.Equal(t, 1, m.Count(1))
+		assert.Equal(t, 3, m.Count(2))
+		assert.Zero(t, m.Count(4))
+	})
+
+	t.Run("Iter", func(t *testing.T) {
+		var ks []int
+		var vs []int
+		for it := m.Iter(); it.Node != nil; it.Next() {
+			ks = append(ks, it.Key.(treap.MultiKey).Key.(int))
+			vs = append(vs, it.Value.(int))
+		}
+
+		assert.Equal(t, []int{1, 2, 2, 2, 3}, ks)
+		assert.Equal(t, []int{10, 30, 10, 20, 5}, vs)
+	})
+
+	t.Run("Pop", func(t *testing.T) {
+		v, _ := m.Handle().Pop(m.Root())
+		assert.Equal(t, 5, v)
+	})
+
+	t.Run("DeleteOne", func(t *testing.T) {
+		m2, ok := m.DeleteOne(2)
+		require.True(t, ok)
+		assert.Equal(t, 4, m2.Len())
+		assert.Equal(t, []int{10, 20}, values(m2.GetAll(2)), "oldest entry should be deleted")
+
+		_, ok = m2.DeleteOne(4)
+		assert.False(t, ok)
+
+		assert.Equal(t, 3, m.Count(2), "original should be unchanged")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		m2, k := m.Insert(2, 99, 0)
+		m2, ok := m2.Delete(k)
+		require.True(t, ok)
+		assert.Equal(t, []int{30, 10, 20}, values(m2.GetAll(2)))
+
+		_, ok = m2.Delete(k)
+		assert.False(t, ok)
+	})
+
+	t.Run("DeleteAll", func(t *testing.T) {
+		m2, n := m.DeleteAll(2)
+		assert.Equal(t, 3, n)
+		assert.Equal(t, 2, m2.Len())
+		assert.Zero(t, m2.Count(2))
+		assert.Equal(t, 1, m2.Count(1))
+		assert.Equal(t, 1, m2.Count(3))
+
+		_, n = m2.DeleteAll(2)
+		assert.Zero(t, n)
+	})
+}
+
+func TestMultiset(t *testing.T) {
+	t.Parallel()
+
+	s := treap.NewMultiset(handle)
+	for i, k := range []int{1, 2, 2, 3, 3, 3} {
+		s = s.Add(k, i)
+	}
+
+	assert.Equal(t, 6, s.Len())
+	assert.Equal(t, 3, s.Count(3))
+
+	s, ok := s.RemoveOne(3)
+	require.True(t, ok)
+	assert.Equal(t, 2, s.Count(3))
+
+	s, n := s.RemoveAll(2)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, 3, s.Len())
+
+	var ks []int
+	for it := s.Iter(); it.Node != nil; it.Next() {
+		ks = append(ks, it.Key.(treap.MultiKey).Key.(int))
+	}
+	assert.Equal(t, []int{1, 3, 3}, ks)
+	assert.Equal(t, 0, s.Root().Weight, "root should have the lowest weight")
+}
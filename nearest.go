@@ -0,0 +1,81 @@
+package treap
+
+// Distance returns the distance between two keys.  It MUST be consistent with the
+// treap's key ordering, i.e. the distance from x MUST NOT decrease as keys move away
+// from x in either direction.
+type Distance func(a, b interface{}) float64
+
+// Nearest returns the node whose key is closest to key.  Ties are broken in favor of
+// the smaller key.  Returns false if the treap is empty.  Key need not be present in
+// the treap.
+//
+// O(log n) if the treap is balanced (see Get).
+func (h Handle) Nearest(n *Node, key interface{}, dist Distance) (*Node, bool) {
+	if ns := h.NearestK(n, key, 1, dist); len(ns) != 0 {
+		return ns[0], true
+	}
+
+	return nil, false
+}
+
+// NearestK returns the k nodes whose keys are closest to key, in increasing order of
+// distance.  Ties are broken in favor of the smaller key.  If the treap contains fewer
+// than k nodes, all of them are returned.
+//
+// NearestK walks outward from the floor and ceiling of key, so it visits O(log n + k)
+// nodes if the treap is balanced (see Get).
+func (h Handle) NearestK(n *Node, key interface{}, k int, dist Distance) []*Node {
+	if n == nil || k <= 0 {
+		return nil
+	}
+
+	var (
+		ns   = make([]*Node, 0, k)
+		up   = h.iterFrom(n, key) // ceiling, and successors
+		down = h.descendBelow(n, key)
+		prev *Node
+	)
+	defer up.Finish()
+	defer func() {
+		for down != nil {
+			_, down = pop(down)
+		}
+	}()
+
+	prev, down = pop(down) // floor
+	for len(ns) < k && (prev != nil || up.Node != nil) {
+		if up.Node == nil || (prev != nil && dist(prev.Key, key) <= dist(up.Key, key)) {
+			ns = append(ns, prev)
+			prev, down = h.predecessor(prev, down)
+		} else {
+			ns = append(ns, up.Node)
+			up.Next()
+		}
+	}
+
+	return ns
+}
+
+// descendBelow returns a stack of the nodes whose keys are less than key, along the
+// search path for key.  The top of the stack is the floor of key.
+func (h Handle) descendBelow(n *Node, key interface{}) (s *stack) {
+	for n != nil {
+		if h.CompareKeys(n.Key, key) < 0 {
+			s = push(s, n)
+			n = n.Right
+		} else {
+			n = n.Left
+		}
+	}
+
+	return
+}
+
+// predecessor pops the node preceding n from the stack built by descendBelow.
+func (h Handle) predecessor(n *Node, s *stack) (*Node, *stack) {
+	for n = n.Left; n != nil; n = n.Right {
+		s = push(s, n)
+	}
+
+	return pop(s)
+}
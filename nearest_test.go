@@ -0,0 +1,79 @@
+package treap_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNearest(t *testing.T) {
+	t.Parallel()
+
+	dist := func(a, b interface{}) float64 {
+		return math.Abs(float64(a.(int) - b.(int)))
+	}
+
+	var root *treap.Node
+	for i, k := range []int{10, 20, 30, 40, 50, 60} {
+		root, _ = handle.Insert(root, k, nil, i)
+	}
+
+	for _, tt := range []struct {
+		desc string
+		key  int
+		want int
+	}{
+		{"exact", 30, 30},
+		{"below", 12, 10},
+		{"above", 18, 20},
+		{"tie", 25, 20},
+		{"min", -100, 10},
+		{"max", 100, 60},
+	} {
+		n, ok := handle.Nearest(root, tt.key, dist)
+		require.True(t, ok, tt.desc)
+		assert.Equal(t, tt.want, n.Key, tt.desc)
+	}
+
+	_, ok := handle.Nearest(nil, 0, dist)
+	assert.False(t, ok, "empty treap should have no nearest key")
+
+	keys := func(ns []*treap.Node) (ks []int) {
+		for _, n := range ns {
+			ks = append(ks, n.Key.(int))
+		}
+		return
+	}
+
+	assert.Equal(t, []int{30, 40, 20, 50}, keys(handle.NearestK(root, 33, 4, dist)))
+	assert.Equal(t, []int{60, 50, 40}, keys(handle.NearestK(root, 70, 3, dist)))
+	assert.Equal(t, []int{20, 30, 10, 40, 50, 60}, keys(handle.NearestK(root, 25, 100, dist)))
+}
+
+func TestNearest_Time(t *testing.T) {
+	t.Parallel()
+
+	h := treap.Handle{
+		CompareKeys:    treap.TimeComparator,
+		CompareWeights: treap.IntComparator,
+	}
+
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var root *treap.Node
+	for i := 0; i < 24; i++ {
+		root, _ = h.Insert(root, t0.Add(time.Duration(i)*time.Hour), i, i)
+	}
+
+	dist := func(a, b interface{}) float64 {
+		return math.Abs(float64(a.(time.Time).Sub(b.(time.Time))))
+	}
+
+	n, ok := h.Nearest(root, t0.Add(5*time.Hour+40*time.Minute), dist)
+	require.True(t, ok)
+	assert.Equal(t, 6, n.Value)
+}
@@ -0,0 +1,182 @@
+package treap
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Queue is a concurrent priority queue backed by a treap.  Pushing a key that is
+// already present updates its value and weight, as per Handle.Upsert.
+//
+// Writers are serialized, and may block until the queue is non-empty (PopWait) or has
+// spare capacity (PushWait).  Reads of the current snapshot are wait-free.
+type Queue struct {
+	h   Handle
+	cap int
+	ptr unsafe.Pointer // *queueState
+
+	mu      sync.Mutex
+	changed chan struct{} // closed and replaced whenever the queue changes
+}
+
+// queueState is an immutable snapshot of a queue.
+type queueState struct {
+	root *Node
+	len  int
+}
+
+// NewQueue returns an empty queue that holds at most capacity items.  If capacity is
+// zero, the queue is unbounded.
+func NewQueue(h Handle, capacity int) *Queue {
+	return &Queue{
+		h:       h,
+		cap:     capacity,
+		ptr:     unsafe.Pointer(&queueState{}),
+		changed: make(chan struct{}),
+	}
+}
+
+// Len returns the number of items in the queue.
+func (q *Queue) Len() int {
+	return q.load().len
+}
+
+// Snapshot returns the root of the queue's treap.
+func (q *Queue) Snapshot() *Node {
+	return q.load().root
+}
+
+// Peek returns the item with the lowest weight, without removing it.  Returns false
+// if the queue is empty.
+func (q *Queue) Peek() (*Node, bool) {
+	root := q.load().root
+	return root, root != nil
+}
+
+// TryPush adds an item to the queue, or updates it if the key is already present.
+// Returns false if the key is absent and the queue is full.
+func (q *Queue) TryPush(key, val, weight interface{}) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.push(key, val, weight)
+}
+
+// PushWait adds an item to the queue, or updates it if the key is already present.
+// If the key is absent and the queue is full, PushWait blocks until space becomes
+// available, or until ctx expires.
+func (q *Queue) PushWait(ctx context.Context, key, val, weight interface{}) error {
+	for {
+		q.mu.Lock()
+		if q.push(key, val, weight) {
+			q.mu.Unlock()
+			return nil
+		}
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// TryPop removes the item with the lowest weight.  The returned node is the former
+// root of the treap; its Left and Right fields SHOULD be ignored.  Returns false if
+// the queue is empty.
+func (q *Queue) TryPop() (*Node, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.pop()
+}
+
+// PopWait removes the item with the lowest weight, blocking until an item is
+// available or ctx expires.  The returned node is the former root of the treap; its
+// Left and Right fields SHOULD be ignored.
+func (q *Queue) PopWait(ctx context.Context) (*Node, error) {
+	for {
+		q.mu.Lock()
+		if n, ok := q.pop(); ok {
+			q.mu.Unlock()
+			return n, nil
+		}
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Delete an item from the queue, returning false if it was not present.
+func (q *Queue) Delete(key interface{}) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s := q.load()
+	if _, ok := q.h.GetNode(s.root, key); !ok {
+		return false
+	}
+
+	q.store(&queueState{
+		root: q.h.Delete(s.root, key),
+		len:  s.len - 1,
+	})
+
+	return true
+}
+
+// push an item.  The caller MUST hold q.mu.
+func (q *Queue) push(key, val, weight interface{}) bool {
+	s := q.load()
+	if q.cap > 0 && s.len >= q.cap {
+		if _, ok := q.h.GetNode(s.root, key); !ok {
+			return false // full
+		}
+	}
+
+	root, created := q.h.Upsert(s.root, key, val, weight)
+
+	new := &queueState{root: root, len: s.len}
+	if created {
+		new.len++
+	}
+
+	q.store(new)
+	return true
+}
+
+// pop an item.  The caller MUST hold q.mu.
+func (q *Queue) pop() (*Node, bool) {
+	s := q.load()
+	if s.root == nil {
+		return nil, false
+	}
+
+	q.store(&queueState{
+		root: q.h.Merge(s.root.Left, s.root.Right),
+		len:  s.len - 1,
+	})
+
+	return s.root, true
+}
+
+func (q *Queue) load() *queueState {
+	return (*queueState)(atomic.LoadPointer(&q.ptr))
+}
+
+// store a new state, and wake up blocked callers.  The caller MUST hold q.mu.
+func (q *Queue) store(s *queueState) {
+	atomic.StorePointer(&q.ptr, unsafe.Pointer(s))
+
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
@@ -0,0 +1,111 @@
+package treap_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("PopOrder", func(t *testing.T) {
+		q := treap.NewQueue(handle, 0)
+
+		_, ok := q.TryPop()
+		assert.False(t, ok, "empty queue should not pop")
+
+		cs := mkTestCases(100)
+		for _, tc := range cs {
+			require.True(t, q.TryPush(tc.key, tc.value, tc.weight))
+		}
+		assert.Equal(t, len(cs), q.Len())
+
+		head, ok := q.Peek()
+		require.True(t, ok)
+		assert.Equal(t, head, q.Snapshot())
+
+		prev := -1
+		for n, ok := q.TryPop(); ok; n, ok = q.TryPop() {
+			assert.LessOrEqual(t, prev, n.Weight.(int))
+			prev = n.Weight.(int)
+		}
+		assert.Zero(t, q.Len())
+	})
+
+	t.Run("Capacity", func(t *testing.T) {
+		q := treap.NewQueue(handle, 2)
+
+		assert.True(t, q.TryPush(1, "a", 1))
+		assert.True(t, q.TryPush(2, "b", 2))
+		assert.False(t, q.TryPush(3, "c", 3), "push should fail when queue is full")
+		assert.True(t, q.TryPush(2, "B", 0), "update should succeed when queue is full")
+		assert.Equal(t, 2, q.Len())
+
+		assert.True(t, q.Delete(1))
+		assert.False(t, q.Delete(1))
+		assert.True(t, q.TryPush(3, "c", 3))
+	})
+
+	t.Run("PopWait", func(t *testing.T) {
+		q := treap.NewQueue(handle, 0)
+
+		got := make(chan *treap.Node)
+		go func() {
+			n, err := q.PopWait(context.Background())
+			assert.NoError(t, err)
+			got <- n
+		}()
+
+		select {
+		case <-got:
+			t.Fatal("PopWait should block on an empty queue")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		require.True(t, q.TryPush(1, "a", 1))
+		assert.Equal(t, "a", (<-got).Value)
+	})
+
+	t.Run("PushWait", func(t *testing.T) {
+		q := treap.NewQueue(handle, 1)
+		require.True(t, q.TryPush(1, "a", 1))
+
+		done := make(chan error)
+		go func() {
+			done <- q.PushWait(context.Background(), 2, "b", 2)
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("PushWait should block on a full queue")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		_, ok := q.TryPop()
+		require.True(t, ok)
+		assert.NoError(t, <-done)
+
+		n, ok := q.Peek()
+		require.True(t, ok)
+		assert.Equal(t, "b", n.Value)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		q := treap.NewQueue(handle, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+
+		_, err := q.PopWait(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		require.True(t, q.TryPush(1, "a", 1))
+		err = q.PushWait(ctx, 2, "b", 2)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
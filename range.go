@@ -0,0 +1,69 @@
+package treap
+
+// Bounds specifies which endpoints of a key range are included in the range.  The
+// zero value is ClosedOpen.
+type Bounds uint8
+
+const (
+	// ClosedOpen includes the lower bound, and excludes the upper bound:  [lo, hi).
+	ClosedOpen Bounds = iota
+	// Closed includes both bounds:  [lo, hi].
+	Closed
+	// OpenClosed excludes the lower bound, and includes the upper bound:  (lo, hi].
+	OpenClosed
+	// Open excludes both bounds:  (lo, hi).
+	Open
+)
+
+func (b Bounds) includesLo() bool { return b == ClosedOpen || b == Closed }
+func (b Bounds) includesHi() bool { return b == Closed || b == OpenClosed }
+
+// DeleteRange removes every key in the range between lo and hi.  The endpoints are
+// included or excluded as per b.
+//
+// O(log n) if the treap is balanced (see Get), regardless of the number of keys in the
+// range.
+func (h Handle) DeleteRange(n *Node, lo, hi interface{}, b Bounds) *Node {
+	n, _ = h.ExtractRange(n, lo, hi, b)
+	return n
+}
+
+// ExtractRange removes every key in the range between lo and hi, and returns both the
+// remaining treap and a treap containing the removed keys.  The endpoints are included
+// or excluded as per b.
+//
+// ExtractRange performs two splits and one merge, so it is O(log n) if the treap is
+// balanced (see Get), regardless of the number of keys in the range.
+func (h Handle) ExtractRange(n *Node, lo, hi interface{}, b Bounds) (rest, extracted *Node) {
+	left, right := h.splitAt(n, lo, !b.includesLo())
+	extracted, right = h.splitAt(right, hi, b.includesHi())
+	return h.Merge(left, right), extracted
+}
+
+// splitAt is like Split, but retains the node whose key is equal to key, if any.  It is
+// placed in the left treap if keyLeft is true, and in the right treap otherwise.
+func (h Handle) splitAt(n *Node, key interface{}, keyLeft bool) (*Node, *Node) {
+	if n == nil {
+		return nil, nil
+	}
+
+	if comp := h.CompareKeys(key, n.Key); comp < 0 || (comp == 0 && !keyLeft) {
+		left, right := h.splitAt(n.Left, key, keyLeft)
+		return left, &Node{
+			Key:    n.Key,
+			Value:  n.Value,
+			Weight: n.Weight,
+			Left:   right,
+			Right:  n.Right,
+		}
+	}
+
+	left, right := h.splitAt(n.Right, key, keyLeft)
+	return &Node{
+		Key:    n.Key,
+		Value:  n.Value,
+		Weight: n.Weight,
+		Left:   n.Left,
+		Right:  left,
+	}, right
+}
@@ -0,0 +1,77 @@
+package treap_test
+
+import (
+	"testing"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractRange(t *testing.T) {
+	t.Parallel()
+
+	root := newPrefilledTreap(handle, 20)
+
+	keys := func(n *treap.Node) (ks []int) {
+		for it := handle.Iter(n); it.Node != nil; it.Next() {
+			ks = append(ks, it.Key.(int))
+		}
+		return
+	}
+
+	for _, tt := range []struct {
+		desc      string
+		bounds    treap.Bounds
+		extracted []int
+	}{
+		{"ClosedOpen", treap.ClosedOpen, []int{5, 6, 7, 8, 9}},
+		{"Closed", treap.Closed, []int{5, 6, 7, 8, 9, 10}},
+		{"OpenClosed", treap.OpenClosed, []int{6, 7, 8, 9, 10}},
+		{"Open", treap.Open, []int{6, 7, 8, 9}},
+	} {
+		rest, extracted := handle.ExtractRange(root, 5, 10, tt.bounds)
+		assert.Equal(t, tt.extracted, keys(extracted), tt.desc)
+		assert.Len(t, keys(rest), 20-len(tt.extracted), tt.desc)
+
+		for _, k := range tt.extracted {
+			_, ok := handle.Get(rest, k)
+			assert.False(t, ok, "%s: %d should be removed", tt.desc, k)
+		}
+
+		assertHeap(t, handle, rest)
+		assertHeap(t, handle, extracted)
+	}
+
+	assert.Len(t, keys(root), 20, "original should be unchanged")
+}
+
+func TestDeleteRange(t *testing.T) {
+	t.Parallel()
+
+	root := newPrefilledTreap(handle, 100)
+
+	n := handle.DeleteRange(root, 0, 50, treap.ClosedOpen)
+	for it := handle.Iter(n); it.Node != nil; it.Next() {
+		assert.GreaterOrEqual(t, it.Key.(int), 50)
+	}
+
+	assert.Nil(t, handle.DeleteRange(root, -1, 100, treap.Open), "deleting every key should yield an empty treap")
+	assert.Equal(t, root, handle.DeleteRange(root, 200, 300, treap.Closed), "deleting an empty range should be a nop")
+	assert.Nil(t, handle.DeleteRange(nil, 0, 1, treap.Closed))
+}
+
+// assertHeap checks that every node's weight is no less than its parent's.
+func assertHeap(t *testing.T, h treap.Handle, n *treap.Node) {
+	t.Helper()
+
+	if n == nil {
+		return
+	}
+
+	for _, child := range []*treap.Node{n.Left, n.Right} {
+		if child != nil {
+			assert.LessOrEqual(t, h.CompareWeights(n.Weight, child.Weight), 0, "heap property violated")
+			assertHeap(t, h, child)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package treap
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Reservoir maintains a weighted random sample of fixed size over a stream of items,
+// without replacement, using the A-Res algorithm of Efraimidis and Spirakis.  Each item
+// is assigned the random priority u^(1/w), where u is uniform on (0, 1] and w is the
+// item's weight, and the sample consists of the items with the k highest priorities.
+//
+// Candidates are held in a Bounded treap, whose minimum-priority root is discarded
+// when a stronger candidate arrives.  Priorities are stored as log(u)/w, which has the
+// same ordering as u^(1/w), but does not underflow for small weights.
+//
+// Reservoir is NOT thread-safe.
+type Reservoir struct {
+	b   Bounded
+	rng *rand.Rand
+	seq uint64
+}
+
+// reservoirHandle orders candidates by arrival, and by priority.
+var reservoirHandle = Handle{
+	CompareKeys:    UInt64Comparator,
+	CompareWeights: Float64Comparator,
+}
+
+// NewReservoir returns an empty reservoir that samples k items.  If k <= 0, the sample
+// is always empty.  If rng is nil, a generator seeded with the current time is used.
+// Pass a seeded generator for reproducible samples.
+func NewReservoir(k int, rng *rand.Rand) *Reservoir {
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	r := &Reservoir{rng: rng}
+	if k > 0 {
+		r.b = NewBounded(reservoirHandle, k)
+	}
+
+	return r
+}
+
+// Add an item to the stream.  The probability that the item is included in the sample
+// is proportional to its weight.  Items with non-positive weights are never sampled.
+func (r *Reservoir) Add(item interface{}, weight float64) {
+	if weight <= 0 || r.b.Cap() == 0 {
+		return
+	}
+
+	priority := math.Log(1-r.rng.Float64()) / weight
+
+	r.b, _ = r.b.Insert(r.seq, item, priority)
+	r.seq++
+}
+
+// Len returns the number of items in the sample, which is the smaller of k and the
+// number of items with positive weights added so far.
+func (r *Reservoir) Len() int {
+	return r.b.Len()
+}
+
+// Sample returns the sampled items, in the order in which they were added.
+func (r *Reservoir) Sample() []interface{} {
+	items := make([]interface{}, 0, r.b.Len())
+	it := reservoirHandle.Iter(r.b.Root())
+	defer it.Finish()
+
+	for ; it.Node != nil; it.Next() {
+		items = append(items, it.Value)
+	}
+
+	return items
+}
+
+// SampleWeighted returns a weighted random sample of k nodes from the treap, without
+// replacement, in key order.  The probability that a node is included in the sample is
+// proportional to weight(node).  If weight is nil, the node's Weight is used, and MUST
+// be a float64.  If rng is nil, a generator seeded with the current time is used.  If
+// k <= 0, the sample is empty.
+//
+// O(n log k)
+func (h Handle) SampleWeighted(n *Node, k int, weight func(*Node) float64, rng *rand.Rand) []*Node {
+	if k <= 0 {
+		return nil
+	}
+
+	if weight == nil {
+		weight = func(n *Node) float64 { return n.Weight.(float64) }
+	}
+
+	r := NewReservoir(k, rng)
+	it := h.Iter(n)
+	defer it.Finish()
+
+	for ; it.Node != nil; it.Next() {
+		r.Add(it.Node, weight(it.Node))
+	}
+
+	sample := make([]*Node, 0, r.Len())
+	for _, item := range r.Sample() {
+		sample = append(sample, item.(*Node))
+	}
+
+	return sample
+}
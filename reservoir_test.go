@@ -0,0 +1,108 @@
+package treap_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReservoir(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Small", func(t *testing.T) {
+		r := treap.NewReservoir(5, rand.New(rand.NewSource(1)))
+		r.Add("a", 1)
+		r.Add("b", 0) // never sampled
+		r.Add("c", 2)
+
+		assert.Equal(t, 2, r.Len())
+		assert.Equal(t, []interface{}{"a", "c"}, r.Sample())
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		for _, k := range []int{0, -1} {
+			r := treap.NewReservoir(k, nil)
+			r.Add("a", 1)
+
+			assert.Zero(t, r.Len(), "k=%d", k)
+			assert.Empty(t, r.Sample(), "k=%d", k)
+		}
+	})
+
+	t.Run("Reproducible", func(t *testing.T) {
+		sample := func() []interface{} {
+			r := treap.NewReservoir(10, rand.New(rand.NewSource(42)))
+			for i := 0; i < 1000; i++ {
+				r.Add(i, float64(i%7+1))
+			}
+			return r.Sample()
+		}
+
+		s := sample()
+		assert.Len(t, s, 10)
+		assert.Equal(t, s, sample())
+	})
+
+	t.Run("Distribution", func(t *testing.T) {
+		// Sampling 1 of 2 items with weights 1 and 3 should pick the heavier
+		// item roughly 75% of the time.
+		const trials = 10000
+
+		var (
+			rng   = rand.New(rand.NewSource(7))
+			heavy int
+		)
+
+		for i := 0; i < trials; i++ {
+			r := treap.NewReservoir(1, rng)
+			r.Add("light", 1)
+			r.Add("heavy", 3)
+
+			if r.Sample()[0] == "heavy" {
+				heavy++
+			}
+		}
+
+		assert.InDelta(t, 0.75, float64(heavy)/trials, 0.02)
+	})
+}
+
+func TestSampleWeighted(t *testing.T) {
+	t.Parallel()
+
+	var root *treap.Node
+	for i := 0; i < 100; i++ {
+		root, _ = handle.Insert(root, i, nil, i)
+	}
+
+	weight := func(n *treap.Node) float64 {
+		if n.Key.(int)%2 == 0 {
+			return 0 // exclude even keys
+		}
+		return 1
+	}
+
+	sample := handle.SampleWeighted(root, 20, weight, rand.New(rand.NewSource(3)))
+	require.Len(t, sample, 20)
+
+	seen := make(map[int]bool)
+	for i, n := range sample {
+		assert.Equal(t, 1, n.Key.(int)%2, "zero-weight node should not be sampled")
+		assert.False(t, seen[n.Key.(int)], "sample should be without replacement")
+		seen[n.Key.(int)] = true
+
+		if i > 0 {
+			assert.Less(t, sample[i-1].Key.(int), n.Key.(int), "sample should be in key order")
+		}
+	}
+
+	all := handle.SampleWeighted(root, 1000, weight, nil)
+	assert.Len(t, all, 50, "sample should include every node with positive weight")
+
+	for _, k := range []int{0, -1} {
+		assert.Empty(t, handle.SampleWeighted(root, k, weight, nil), "k=%d", k)
+	}
+}
@@ -0,0 +1,185 @@
+package treap
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// Scheduler is a delay queue.  Each scheduled item is keyed, and carries a deadline,
+// which is used as its weight.  A callback is invoked for each item when its deadline
+// is due, in deadline order.  Items can be rescheduled or cancelled by key.
+//
+// Writers are serialized.  Reads are wait-free.
+type Scheduler struct {
+	h     Handle
+	clock Clock
+	fire  func(key, value interface{})
+	ptr   unsafe.Pointer // *Node
+
+	firing sync.Mutex // serializes calls to fire
+
+	mu       sync.Mutex
+	timer    Timer     // fires when the earliest deadline is due
+	deadline time.Time // deadline for which timer is armed
+	gen      uint64    // incremented each time the timer is armed
+	stopped  bool
+}
+
+// NewScheduler returns a scheduler whose keys are ordered by compareKeys.  The fire
+// callback is invoked from the timer's goroutine when an item is due, and SHOULD NOT
+// block.  Calls to fire never overlap, so it need not be safe for concurrent use.  If
+// clock is nil, SystemClock is used.
+func NewScheduler(compareKeys Comparator, clock Clock, fire func(key, value interface{})) *Scheduler {
+	if clock == nil {
+		clock = SystemClock
+	}
+
+	return &Scheduler{
+		h: Handle{
+			CompareKeys:    compareKeys,
+			CompareWeights: TimeComparator,
+		},
+		clock: clock,
+		fire:  fire,
+	}
+}
+
+// Snapshot returns the root of the scheduler's treap.  Weights are deadlines of type
+// time.Time.
+func (s *Scheduler) Snapshot() *Node {
+	return (*Node)(atomic.LoadPointer(&s.ptr))
+}
+
+// Deadline returns the deadline for the specified key.  Returns false if the key is
+// not scheduled.
+func (s *Scheduler) Deadline(key interface{}) (time.Time, bool) {
+	if n, ok := s.h.GetNode(s.Snapshot(), key); ok {
+		return n.Weight.(time.Time), true
+	}
+
+	return time.Time{}, false
+}
+
+// Schedule value to fire at the specified time.  If the key is already scheduled, its
+// value and deadline are replaced, and Schedule returns false.
+func (s *Scheduler) Schedule(key, value interface{}, at time.Time) (created bool) {
+	s.update(func(root *Node) *Node {
+		root, created = s.h.Upsert(root, key, value, at)
+		return root
+	})
+
+	return
+}
+
+// Reschedule changes the deadline of the specified key.  Returns false if the key is
+// not scheduled.
+func (s *Scheduler) Reschedule(key interface{}, at time.Time) (ok bool) {
+	s.update(func(root *Node) *Node {
+		var new *Node
+		if new, ok = s.h.SetWeight(root, key, at); ok {
+			return new
+		}
+
+		return root
+	})
+
+	return
+}
+
+// Cancel the specified key.  Returns false if the key is not scheduled.
+func (s *Scheduler) Cancel(key interface{}) (ok bool) {
+	s.update(func(root *Node) *Node {
+		if _, ok = s.h.GetNode(root, key); ok {
+			return s.h.Delete(root, key)
+		}
+
+		return root
+	})
+
+	return
+}
+
+// Stop the scheduler.  Pending items will not fire.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stopped = true
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+func (s *Scheduler) update(f func(*Node) *Node) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.store(f(s.Snapshot()))
+	s.arm()
+}
+
+// run fires all items that are due.  Gen identifies the timer that called run.
+func (s *Scheduler) run(gen uint64) {
+	// The timer for the next deadline is armed before the due items are fired, and
+	// may itself fire immediately.  Holding s.firing from the pop until the last
+	// callback returns ensures that it waits for them, and so preserves the order.
+	s.firing.Lock()
+	defer s.firing.Unlock()
+
+	s.mu.Lock()
+	if s.stopped || gen != s.gen { // stale timer
+		s.mu.Unlock()
+		return
+	}
+
+	var (
+		now = s.clock.Now()
+		due []*Node
+	)
+
+	s.store(s.h.PopWhile(s.Snapshot(), func(n *Node) bool {
+		if n.Weight.(time.Time).After(now) {
+			return false
+		}
+
+		due = append(due, n)
+		return true
+	}))
+	s.timer = nil // this timer has fired
+	s.arm()
+	s.mu.Unlock()
+
+	for _, n := range due {
+		s.fire(n.Key, n.Value)
+	}
+}
+
+// arm the timer for the earliest deadline.  The caller MUST hold s.mu.
+func (s *Scheduler) arm() {
+	root := s.Snapshot()
+	if s.stopped || (s.timer != nil && root != nil && root.Weight.(time.Time).Equal(s.deadline)) {
+		return
+	}
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+
+	if root != nil {
+		s.gen++
+		gen := s.gen
+
+		s.deadline = root.Weight.(time.Time)
+		s.timer = s.clock.AfterFunc(s.deadline.Sub(s.clock.Now()), func() {
+			s.run(gen)
+		})
+	}
+}
+
+func (s *Scheduler) store(n *Node) {
+	atomic.StorePointer(&s.ptr, unsafe.Pointer(n))
+}
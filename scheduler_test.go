@@ -0,0 +1,141 @@
+package treap_test
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	start := clock.Now()
+
+	var fired []string
+	s := treap.NewScheduler(treap.StringComparator, clock, func(key, value interface{}) {
+		fired = append(fired, key.(string))
+	})
+
+	assert.True(t, s.Schedule("c", 3, start.Add(3*time.Second)))
+	assert.True(t, s.Schedule("a", 1, start.Add(time.Second)))
+	assert.True(t, s.Schedule("b", 2, start.Add(2*time.Second)))
+	assert.True(t, s.Schedule("d", 4, start.Add(4*time.Second)))
+	assert.Equal(t, 1, clock.Pending(), "only the earliest deadline should be armed")
+
+	at, ok := s.Deadline("b")
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(2*time.Second), at)
+
+	clock.Advance(999 * time.Millisecond)
+	assert.Empty(t, fired, "nothing should fire before its deadline")
+
+	clock.Advance(time.Millisecond)
+	assert.Equal(t, []string{"a"}, fired, "item should fire exactly at its deadline")
+
+	t.Run("Reschedule", func(t *testing.T) {
+		assert.True(t, s.Reschedule("c", start.Add(1500*time.Millisecond)))
+		assert.False(t, s.Reschedule("missing", start))
+
+		clock.Advance(500 * time.Millisecond)
+		assert.Equal(t, []string{"a", "c"}, fired)
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		assert.True(t, s.Cancel("b"))
+		assert.False(t, s.Cancel("b"))
+
+		_, ok := s.Deadline("b")
+		assert.False(t, ok)
+
+		clock.Advance(time.Second)
+		assert.Equal(t, []string{"a", "c"}, fired, "cancelled item should not fire")
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		assert.False(t, s.Schedule("d", 4, start.Add(10*time.Second)))
+
+		clock.Advance(2 * time.Second)
+		assert.Equal(t, []string{"a", "c"}, fired, "updated deadline should be honoured")
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		s.Stop()
+		clock.Advance(time.Hour)
+		assert.Equal(t, []string{"a", "c"}, fired, "stopped scheduler should not fire")
+		assert.Zero(t, clock.Pending())
+	})
+}
+
+func TestScheduler_SameDeadline(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	at := clock.Now().Add(time.Second)
+
+	var fired []int
+	s := treap.NewScheduler(treap.IntComparator, clock, func(key, value interface{}) {
+		fired = append(fired, key.(int))
+	})
+
+	for i := 0; i < 10; i++ {
+		s.Schedule(i, nil, at)
+	}
+
+	clock.Advance(time.Second)
+	assert.Len(t, fired, 10, "all items sharing a deadline should fire together")
+	assert.Nil(t, s.Snapshot())
+}
+
+func TestScheduler_SystemClock(t *testing.T) {
+	t.Parallel()
+
+	const n = 100
+
+	var (
+		mu                sync.Mutex
+		fired             []int
+		active, maxActive int
+		done              = make(chan struct{})
+	)
+
+	s := treap.NewScheduler(treap.IntComparator, nil, func(key, value interface{}) {
+		mu.Lock()
+		if active++; active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond) // slow callback, so that later deadlines pass
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		active--
+		if fired = append(fired, key.(int)); len(fired) == n {
+			close(done)
+		}
+	})
+	defer s.Stop()
+
+	start := time.Now().Add(time.Millisecond)
+	for i := 0; i < n; i++ {
+		s.Schedule(i, nil, start.Add(time.Duration(i)*50*time.Microsecond))
+	}
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("items did not fire")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.True(t, sort.IntsAreSorted(fired), "items should fire in deadline order")
+	assert.Equal(t, 1, maxActive, "calls to fire should not overlap")
+}
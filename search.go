@@ -0,0 +1,126 @@
+package treap
+
+import (
+	"bytes"
+	"container/heap"
+	"strings"
+)
+
+// MinWeightInRange returns the node with the lowest weight whose key lies in the
+// half-open interval [lo, hi).  Returns false if no key lies in the interval.
+//
+// Because a treap is a priority search tree, this does not scan the interval.
+// O(log n) if the treap is balanced (see Get).
+func (h Handle) MinWeightInRange(n *Node, lo, hi interface{}) (*Node, bool) {
+	if ns := h.TopKInRange(n, lo, hi, 1); len(ns) != 0 {
+		return ns[0], true
+	}
+
+	return nil, false
+}
+
+// TopKInRange returns the k nodes with the lowest weights whose keys lie in the
+// half-open interval [lo, hi), in increasing order of weight.  If fewer than k keys lie
+// in the interval, all of them are returned.  Use MaxTreap to obtain the k nodes with
+// the highest weights.
+//
+// The search is best-first:  subtrees are visited in order of their root's weight, and
+// the heap property guarantees that a subtree cannot contain a better candidate than
+// its root.  Subtrees that are heavier than the k-th best candidate are therefore never
+// visited.
+//
+// Visits O(log n + k) nodes if the treap is balanced (see Get).
+func (h Handle) TopKInRange(n *Node, lo, hi interface{}, k int) []*Node {
+	return h.topK(n, k, func(key interface{}) int {
+		switch {
+		case h.CompareKeys(key, lo) < 0:
+			return -1
+		case h.CompareKeys(key, hi) >= 0:
+			return 1
+		default:
+			return 0
+		}
+	})
+}
+
+// topK returns the k lightest nodes whose keys are in range.  The locate function
+// reports whether a key is below (-1), within (0) or above (1) the range, which MUST be
+// contiguous in key-order.
+func (h Handle) topK(n *Node, k int, locate func(key interface{}) int) []*Node {
+	if n == nil || k <= 0 {
+		return nil
+	}
+
+	var (
+		ns         []*Node
+		candidates = &nodeHeap{compare: h.CompareWeights, ns: []*Node{n}}
+	)
+
+	for len(ns) < k && candidates.Len() != 0 {
+		n := heap.Pop(candidates).(*Node)
+
+		pos := locate(n.Key)
+		if pos == 0 {
+			ns = append(ns, n)
+		}
+
+		if pos >= 0 && n.Left != nil { // the range may extend to the left
+			heap.Push(candidates, n.Left)
+		}
+
+		if pos <= 0 && n.Right != nil { // the range may extend to the right
+			heap.Push(candidates, n.Right)
+		}
+	}
+
+	return ns
+}
+
+// nodeHeap is a min-heap of nodes, ordered by weight.
+type nodeHeap struct {
+	compare Comparator
+	ns      []*Node
+}
+
+func (h nodeHeap) Len() int            { return len(h.ns) }
+func (h nodeHeap) Less(i, j int) bool  { return h.compare(h.ns[i].Weight, h.ns[j].Weight) < 0 }
+func (h nodeHeap) Swap(i, j int)       { h.ns[i], h.ns[j] = h.ns[j], h.ns[i] }
+func (h *nodeHeap) Push(x interface{}) { h.ns = append(h.ns, x.(*Node)) }
+
+func (h *nodeHeap) Pop() interface{} {
+	n := h.ns[len(h.ns)-1]
+	h.ns = h.ns[:len(h.ns)-1]
+	return n
+}
+
+// TopKByPrefix returns the k nodes with the lowest weights whose keys begin with
+// prefix, in increasing order of weight.  Use MaxTreap to obtain the k nodes with the
+// highest weights, e.g. the most popular completions.  Keys MUST be either strings or
+// []byte, and prefix MUST have the same type.  As with PrefixIter, keys are assumed to
+// be ordered bytewise.
+//
+// As with TopKInRange, subtrees are pruned using the heap property, so matching keys
+// are not scanned.
+func (h Handle) TopKByPrefix(n *Node, prefix interface{}, k int) []*Node {
+	return h.topK(n, k, func(key interface{}) int {
+		switch {
+		case hasPrefix(key, prefix):
+			return 0
+		case h.CompareKeys(key, prefix) < 0:
+			return -1
+		default:
+			return 1
+		}
+	})
+}
+
+func hasPrefix(key, prefix interface{}) bool {
+	switch p := prefix.(type) {
+	case string:
+		return strings.HasPrefix(key.(string), p)
+	case []byte:
+		return bytes.HasPrefix(key.([]byte), p)
+	default:
+		panic("treap: prefix must be a string or []byte")
+	}
+}
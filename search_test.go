@@ -0,0 +1,126 @@
+package treap_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinWeightInRange(t *testing.T) {
+	t.Parallel()
+
+	root := newPrefilledTreap(handle, 1000)
+
+	for _, r := range []struct{ lo, hi int }{
+		{0, 1000},
+		{100, 200},
+		{500, 501},
+		{-10, 10},
+	} {
+		n, ok := handle.MinWeightInRange(root, r.lo, r.hi)
+		require.True(t, ok)
+
+		want := -1
+		for it := handle.Iter(root); it.Node != nil; it.Next() {
+			if k := it.Key.(int); k >= r.lo && k < r.hi {
+				if w := it.Weight.(int); want < 0 || w < want {
+					want = w
+				}
+			}
+		}
+
+		assert.Equal(t, want, n.Weight)
+		assert.GreaterOrEqual(t, n.Key.(int), r.lo)
+		assert.Less(t, n.Key.(int), r.hi)
+	}
+
+	_, ok := handle.MinWeightInRange(root, 2000, 3000)
+	assert.False(t, ok, "empty range should not match")
+
+	_, ok = handle.MinWeightInRange(nil, 0, 10)
+	assert.False(t, ok, "empty treap should not match")
+}
+
+func TestTopKInRange(t *testing.T) {
+	t.Parallel()
+
+	root := newPrefilledTreap(handle, 1000)
+
+	var want []int
+	for it := handle.Iter(root); it.Node != nil; it.Next() {
+		if k := it.Key.(int); k >= 250 && k < 750 {
+			want = append(want, it.Weight.(int))
+		}
+	}
+	sort.Ints(want)
+
+	got := func(ns []*treap.Node) (ws []int) {
+		for _, n := range ns {
+			ws = append(ws, n.Weight.(int))
+		}
+		return
+	}
+
+	assert.Equal(t, want[:10], got(handle.TopKInRange(root, 250, 750, 10)))
+	assert.Equal(t, want, got(handle.TopKInRange(root, 250, 750, 10000)),
+		"should return every node in range if k is large")
+	assert.Empty(t, handle.TopKInRange(root, 250, 750, 0))
+
+	t.Run("MaxTreap", func(t *testing.T) {
+		h := treap.Handle{
+			CompareKeys:    treap.IntComparator,
+			CompareWeights: treap.MaxTreap(treap.IntComparator),
+		}
+
+		var root *treap.Node
+		for i := 0; i < 100; i++ {
+			root, _ = h.Insert(root, i, nil, (i*37)%100)
+		}
+
+		ns := h.TopKInRange(root, 10, 20, 3)
+		require.Len(t, ns, 3)
+
+		var ws []int
+		for i := 10; i < 20; i++ {
+			ws = append(ws, (i*37)%100)
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(ws)))
+
+		assert.Equal(t, ws[:3], got(ns))
+	})
+}
+
+func TestTopKByPrefix(t *testing.T) {
+	t.Parallel()
+
+	// autocomplete:  the most popular terms have the highest weights
+	h := treap.Handle{
+		CompareKeys:    treap.StringComparator,
+		CompareWeights: treap.MaxTreap(treap.IntComparator),
+	}
+
+	var root *treap.Node
+	for term, popularity := range map[string]int{
+		"car":     50,
+		"card":    20,
+		"care":    80,
+		"careful": 10,
+		"cart":    30,
+		"cat":     90,
+		"dog":     100,
+	} {
+		root, _ = h.Insert(root, term, nil, popularity)
+	}
+
+	var got []string
+	for _, n := range h.TopKByPrefix(root, "car", 3) {
+		got = append(got, n.Key.(string))
+	}
+
+	assert.Equal(t, []string{"care", "car", "cart"}, got)
+	assert.Len(t, h.TopKByPrefix(root, "c", 100), 6)
+	assert.Empty(t, h.TopKByPrefix(root, "x", 3))
+}
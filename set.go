@@ -0,0 +1,238 @@
+package treap
+
+// SetNode is the recursive datastructure that defines a persistent, value-less treap.
+// It is identical to Node, but omits the Value field, which reduces the footprint of
+// each node from 64 to 48 bytes on 64-bit platforms.
+//
+// The zero value is ready to use.
+type SetNode struct {
+	Weight, Key interface{}
+	Left, Right *SetNode
+}
+
+// SetHandle performs purely functional transformations on a value-less treap, i.e. an
+// ordered set whose elements are also heap-ordered by weight.  A Handle can be
+// converted to a SetHandle, and vice versa.
+type SetHandle Handle
+
+// Contains reports whether the set contains key.
+//
+// O(log n) if the treap is balanced (see Handle.Get).
+func (h SetHandle) Contains(n *SetNode, key interface{}) bool {
+	for n != nil {
+		switch comp := h.CompareKeys(key, n.Key); {
+		case comp < 0:
+			n = n.Left
+		case comp > 0:
+			n = n.Right
+		default:
+			return true
+		}
+	}
+
+	return false
+}
+
+// Add a key to the set, returning false if it is already present.
+//
+// O(log n) if the treap is balanced (see Handle.Get).
+func (h SetHandle) Add(n *SetNode, key, weight interface{}) (*SetNode, bool) {
+	if h.Contains(n, key) {
+		return n, false
+	}
+
+	return h.add(n, key, weight), true
+}
+
+// add a key that is not already present.  The key is inserted at the first node on
+// its search path that is heavier than it, and that node's subtree is split around it.
+func (h SetHandle) add(n *SetNode, key, weight interface{}) *SetNode {
+	if n == nil || h.CompareWeights(weight, n.Weight) < 0 {
+		left, _, right := h.split(n, key)
+		return &SetNode{Key: key, Weight: weight, Left: left, Right: right}
+	}
+
+	if h.CompareKeys(key, n.Key) < 0 {
+		return &SetNode{Key: n.Key, Weight: n.Weight, Left: h.add(n.Left, key, weight), Right: n.Right}
+	}
+
+	return &SetNode{Key: n.Key, Weight: n.Weight, Left: n.Left, Right: h.add(n.Right, key, weight)}
+}
+
+// Remove a key from the set, returning false if it was not present.
+//
+// O(log n) if the treap is balanced (see Handle.Get).
+func (h SetHandle) Remove(n *SetNode, key interface{}) (*SetNode, bool) {
+	if n == nil {
+		return nil, false
+	}
+
+	switch comp := h.CompareKeys(key, n.Key); {
+	case comp < 0:
+		left, ok := h.Remove(n.Left, key)
+		if !ok {
+			return n, false
+		}
+
+		return &SetNode{Key: n.Key, Weight: n.Weight, Left: left, Right: n.Right}, true
+
+	case comp > 0:
+		right, ok := h.Remove(n.Right, key)
+		if !ok {
+			return n, false
+		}
+
+		return &SetNode{Key: n.Key, Weight: n.Weight, Left: n.Left, Right: right}, true
+
+	default:
+		return h.merge(n.Left, n.Right), true
+	}
+}
+
+// Pop the key with the lowest weight off the heap.  Returns nil if the set is empty.
+//
+// O(log n)
+func (h SetHandle) Pop(n *SetNode) (interface{}, *SetNode) {
+	if n == nil {
+		return nil, nil
+	}
+
+	return n.Key, h.merge(n.Left, n.Right)
+}
+
+// Union returns the set of keys that are in either a or b.  If a key is present in
+// both, the lower of its two weights is retained.
+//
+// O(m log(n/m + 1)), where m and n are the sizes of the smaller and larger sets, if
+// both treaps are balanced.
+func (h SetHandle) Union(a, b *SetNode) *SetNode {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case h.CompareWeights(b.Weight, a.Weight) < 0:
+		a, b = b, a
+	}
+
+	// a's root is the lightest node in either set, so it becomes the root.
+	left, _, right := h.split(b, a.Key)
+	return &SetNode{
+		Key:    a.Key,
+		Weight: a.Weight,
+		Left:   h.Union(a.Left, left),
+		Right:  h.Union(a.Right, right),
+	}
+}
+
+// Intersection returns the set of keys that are in both a and b.  The lower of each
+// key's two weights is retained.
+//
+// O(m log(n/m + 1)), where m and n are the sizes of the smaller and larger sets, if
+// both treaps are balanced.
+func (h SetHandle) Intersection(a, b *SetNode) *SetNode {
+	if a == nil || b == nil {
+		return nil
+	}
+
+	if h.CompareWeights(b.Weight, a.Weight) < 0 {
+		a, b = b, a
+	}
+
+	left, found, right := h.split(b, a.Key)
+	left, right = h.Intersection(a.Left, left), h.Intersection(a.Right, right)
+	if found == nil {
+		return h.merge(left, right)
+	}
+
+	return &SetNode{Key: a.Key, Weight: a.Weight, Left: left, Right: right}
+}
+
+// Difference returns the set of keys that are in a, but not in b.  Weights are taken
+// from a.
+//
+// O(m log(n/m + 1)), where m and n are the sizes of the smaller and larger sets, if
+// both treaps are balanced.
+func (h SetHandle) Difference(a, b *SetNode) *SetNode {
+	if a == nil || b == nil {
+		return a
+	}
+
+	left, found, right := h.split(b, a.Key)
+	left, right = h.Difference(a.Left, left), h.Difference(a.Right, right)
+	if found != nil {
+		return h.merge(left, right)
+	}
+
+	return &SetNode{Key: a.Key, Weight: a.Weight, Left: left, Right: right}
+}
+
+// split a set at key, returning the keys below it, the node holding key (or nil), and
+// the keys above it.
+func (h SetHandle) split(n *SetNode, key interface{}) (left, found, right *SetNode) {
+	if n == nil {
+		return
+	}
+
+	switch comp := h.CompareKeys(key, n.Key); {
+	case comp < 0:
+		left, found, right = h.split(n.Left, key)
+		right = &SetNode{Key: n.Key, Weight: n.Weight, Left: right, Right: n.Right}
+	case comp > 0:
+		left, found, right = h.split(n.Right, key)
+		left = &SetNode{Key: n.Key, Weight: n.Weight, Left: n.Left, Right: left}
+	default:
+		left, found, right = n.Left, n, n.Right
+	}
+
+	return
+}
+
+// merge two sets, where every key in left is less than every key in right.
+func (h SetHandle) merge(left, right *SetNode) *SetNode {
+	switch {
+	case left == nil:
+		return right
+	case right == nil:
+		return left
+	case h.CompareWeights(left.Weight, right.Weight) < 0:
+		return &SetNode{Key: left.Key, Weight: left.Weight, Left: left.Left, Right: h.merge(left.Right, right)}
+	default:
+		return &SetNode{Key: right.Key, Weight: right.Weight, Left: h.merge(left, right.Left), Right: right.Right}
+	}
+}
+
+// Iter walks the set in key-order.
+func (h SetHandle) Iter(n *SetNode) *SetIterator {
+	it := &SetIterator{}
+	it.descend(n)
+	it.Next()
+	return it
+}
+
+// SetIterator contains set iteration state.  Its methods are NOT thread-safe, but
+// multiple concurrent iterators are supported.
+type SetIterator struct {
+	*SetNode
+	stack []*SetNode
+}
+
+// Next item.
+func (it *SetIterator) Next() {
+	if it.SetNode != nil {
+		it.descend(it.SetNode.Right)
+	}
+
+	it.SetNode = nil
+	if len(it.stack) != 0 {
+		it.SetNode = it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+	}
+}
+
+// descend pushes n and its chain of left descendants onto the stack.
+func (it *SetIterator) descend(n *SetNode) {
+	for ; n != nil; n = n.Left {
+		it.stack = append(it.stack, n)
+	}
+}
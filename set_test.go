@@ -0,0 +1,145 @@
+package treap_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+	"unsafe"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var setHandle = treap.SetHandle(handle)
+
+func TestSet(t *testing.T) {
+	t.Parallel()
+
+	assert.Less(t, int(unsafe.Sizeof(treap.SetNode{})), int(unsafe.Sizeof(treap.Node{})),
+		"set nodes should be smaller than nodes")
+
+	var s *treap.SetNode
+	for _, k := range []int{5, 3, 8, 1, 4} {
+		var ok bool
+		s, ok = setHandle.Add(s, k, k*10%7)
+		require.True(t, ok)
+	}
+
+	s2, ok := setHandle.Add(s, 3, 0)
+	assert.False(t, ok, "duplicate key should be rejected")
+	assert.Equal(t, s, s2)
+
+	assert.True(t, setHandle.Contains(s, 4))
+	assert.False(t, setHandle.Contains(s, 6))
+	assert.Equal(t, []int{1, 3, 4, 5, 8}, setKeys(s))
+
+	s2, ok = setHandle.Remove(s, 4)
+	require.True(t, ok)
+	assert.False(t, setHandle.Contains(s2, 4))
+	assert.True(t, setHandle.Contains(s, 4), "original should be unchanged")
+
+	_, ok = setHandle.Remove(s2, 4)
+	assert.False(t, ok)
+
+	var popped []int
+	for n := s; n != nil; {
+		var k interface{}
+		k, n = setHandle.Pop(n)
+		popped = append(popped, k.(int)*10%7)
+	}
+	assert.True(t, sort.IntsAreSorted(popped), "keys should pop in weight order")
+
+	k, n := setHandle.Pop(nil)
+	assert.Nil(t, k)
+	assert.Nil(t, n)
+}
+
+func TestSet_Algebra(t *testing.T) {
+	t.Parallel()
+
+	rng := rand.New(rand.NewSource(1))
+
+	mkSet := func(n int) (*treap.SetNode, map[int]int) {
+		var (
+			s     *treap.SetNode
+			model = make(map[int]int)
+		)
+
+		for i := 0; i < n; i++ {
+			k, w := rng.Intn(200), rng.Intn(1000)
+			if _, ok := model[k]; !ok {
+				s, _ = setHandle.Add(s, k, w)
+				model[k] = w
+			}
+		}
+
+		return s, model
+	}
+
+	for i := 0; i < 20; i++ {
+		a, ma := mkSet(rng.Intn(100))
+		b, mb := mkSet(rng.Intn(100))
+
+		union := make(map[int]int)
+		inter := make(map[int]int)
+		diff := make(map[int]int)
+		for k, w := range ma {
+			union[k] = w
+			if wb, ok := mb[k]; ok {
+				if wb < w {
+					w = wb
+				}
+				union[k] = w
+				inter[k] = w
+			} else {
+				diff[k] = w
+			}
+		}
+		for k, w := range mb {
+			if _, ok := ma[k]; !ok {
+				union[k] = w
+			}
+		}
+
+		assertSet(t, union, setHandle.Union(a, b))
+		assertSet(t, inter, setHandle.Intersection(a, b))
+		assertSet(t, diff, setHandle.Difference(a, b))
+	}
+}
+
+// assertSet checks that s holds exactly the keys and weights in want, and that it
+// satisfies the heap property.
+func assertSet(t *testing.T, want map[int]int, s *treap.SetNode) {
+	t.Helper()
+
+	got := make(map[int]int)
+	for it := setHandle.Iter(s); it.SetNode != nil; it.Next() {
+		got[it.Key.(int)] = it.Weight.(int)
+	}
+	assert.Equal(t, want, got)
+
+	keys := setKeys(s)
+	assert.True(t, sort.IntsAreSorted(keys), "keys should be in order")
+
+	var check func(n *treap.SetNode)
+	check = func(n *treap.SetNode) {
+		for _, child := range []*treap.SetNode{n.Left, n.Right} {
+			if child != nil {
+				assert.LessOrEqual(t, n.Weight.(int), child.Weight.(int), "heap property violated")
+				check(child)
+			}
+		}
+	}
+
+	if s != nil {
+		check(s)
+	}
+}
+
+func setKeys(s *treap.SetNode) (ks []int) {
+	for it := setHandle.Iter(s); it.SetNode != nil; it.Next() {
+		ks = append(ks, it.Key.(int))
+	}
+	return
+}
@@ -0,0 +1,239 @@
+package treap
+
+import (
+	"sort"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Sharded is a thread-safe container that partitions its entries across several
+// treaps, each of which is updated independently using a CAS loop.  Writers that
+// touch different shards do not contend with one another.  Reads are wait-free.
+//
+// Keys are assigned to shards either by hash (see NewHashSharded) or by key range
+// (see NewRangeSharded).
+type Sharded struct {
+	h      Handle
+	shards []shard
+	locate func(key interface{}) int
+}
+
+type shard struct {
+	ptr unsafe.Pointer // *shardState
+	_   [56]byte       // pad to a cache line to avoid false sharing
+}
+
+// shardState is an immutable snapshot of a shard.
+type shardState struct {
+	root *Node
+	len  int
+}
+
+// NewHashSharded returns a Sharded container with n shards.  Each key is assigned to
+// shard hash(key) % n.  Panics if n is not positive.
+func NewHashSharded(h Handle, n int, hash func(key interface{}) uint64) *Sharded {
+	if n <= 0 {
+		panic("treap: shard count must be positive")
+	}
+
+	s := newSharded(h, n)
+	s.locate = func(key interface{}) int {
+		return int(hash(key) % uint64(n))
+	}
+
+	return s
+}
+
+// NewRangeSharded returns a Sharded container with len(bounds)+1 shards.  The bounds
+// MUST be sorted in increasing order by h.CompareKeys.  Shard i holds the keys k such
+// that bounds[i-1] <= k < bounds[i].
+func NewRangeSharded(h Handle, bounds ...interface{}) *Sharded {
+	s := newSharded(h, len(bounds)+1)
+	s.locate = func(key interface{}) int {
+		return sort.Search(len(bounds), func(i int) bool {
+			return h.CompareKeys(key, bounds[i]) < 0
+		})
+	}
+
+	return s
+}
+
+func newSharded(h Handle, n int) *Sharded {
+	s := &Sharded{
+		h:      h,
+		shards: make([]shard, n),
+	}
+
+	for i := range s.shards {
+		s.shards[i].ptr = unsafe.Pointer(&shardState{})
+	}
+
+	return s
+}
+
+// Len returns the total number of entries across all shards.
+func (s *Sharded) Len() (n int) {
+	for i := range s.shards {
+		n += s.load(i).len
+	}
+
+	return
+}
+
+// Get an element by key.
+func (s *Sharded) Get(key interface{}) (interface{}, bool) {
+	return s.h.Get(s.load(s.locate(key)).root, key)
+}
+
+// Upsert updates an element, creating one if it is missing.
+func (s *Sharded) Upsert(key, val, weight interface{}) (created bool) {
+	s.update(s.locate(key), func(old *shardState) *shardState {
+		var root *Node
+		root, created = s.h.Upsert(old.root, key, val, weight)
+
+		new := &shardState{root: root, len: old.len}
+		if created {
+			new.len++
+		}
+
+		return new
+	})
+
+	return
+}
+
+// Delete an element by key, returning false if it was not present.
+func (s *Sharded) Delete(key interface{}) (deleted bool) {
+	s.update(s.locate(key), func(old *shardState) *shardState {
+		if _, deleted = s.h.GetNode(old.root, key); !deleted {
+			return old
+		}
+
+		return &shardState{
+			root: s.h.Delete(old.root, key),
+			len:  old.len - 1,
+		}
+	})
+
+	return
+}
+
+// Pop the entry with the lowest weight across all shards.  The returned node is the
+// former root of its shard; its Left and Right fields SHOULD be ignored.  Returns
+// false if the container is empty.
+//
+// In the presence of concurrent writers, the popped entry is the lowest-weighted
+// entry of a recent, but not necessarily atomic, view of the shards.
+func (s *Sharded) Pop() (*Node, bool) {
+	for {
+		var (
+			min   = -1
+			state *shardState
+		)
+
+		for i := range s.shards {
+			st := s.load(i)
+			if st.root == nil {
+				continue
+			}
+
+			if state == nil || s.h.CompareWeights(st.root.Weight, state.root.Weight) < 0 {
+				min, state = i, st
+			}
+		}
+
+		if state == nil {
+			return nil, false
+		}
+
+		new := &shardState{
+			root: s.h.Merge(state.root.Left, state.root.Right),
+			len:  state.len - 1,
+		}
+
+		if atomic.CompareAndSwapPointer(&s.shards[min].ptr,
+			unsafe.Pointer(state),
+			unsafe.Pointer(new),
+		) {
+			return state.root, true
+		}
+	}
+}
+
+// Iter walks a snapshot of every shard in key-order, performing a k-way merge across
+// shards.  As with Iterator, it is NOT thread-safe, but multiple concurrent iterators
+// are supported.
+func (s *Sharded) Iter() *ShardedIterator {
+	it := &ShardedIterator{
+		compare: s.h.CompareKeys,
+		its:     make([]*Iterator, 0, len(s.shards)),
+	}
+
+	for i := range s.shards {
+		if root := s.load(i).root; root != nil {
+			it.its = append(it.its, s.h.Iter(root))
+		}
+	}
+
+	it.advance()
+	return it
+}
+
+func (s *Sharded) load(i int) *shardState {
+	return (*shardState)(atomic.LoadPointer(&s.shards[i].ptr))
+}
+
+func (s *Sharded) update(i int, f func(*shardState) *shardState) {
+	for {
+		old := s.load(i)
+		if new := f(old); new == old || atomic.CompareAndSwapPointer(&s.shards[i].ptr,
+			unsafe.Pointer(old),
+			unsafe.Pointer(new),
+		) {
+			return
+		}
+	}
+}
+
+// ShardedIterator contains the iteration state for a Sharded container.
+type ShardedIterator struct {
+	*Node
+	compare Comparator
+	its     []*Iterator
+}
+
+// Next item.
+func (it *ShardedIterator) Next() {
+	for i, sub := range it.its {
+		if sub.Node == it.Node {
+			if sub.Next(); sub.Node == nil {
+				sub.Finish()
+				it.its = append(it.its[:i], it.its[i+1:]...)
+			}
+
+			break
+		}
+	}
+
+	it.advance()
+}
+
+// Finish SHOULD be called if the iterator is abandoned before it is exhausted.
+func (it *ShardedIterator) Finish() {
+	for _, sub := range it.its {
+		sub.Finish()
+	}
+
+	it.its = nil
+	it.Node = nil
+}
+
+// advance sets it.Node to the smallest head across sub-iterators.
+func (it *ShardedIterator) advance() {
+	it.Node = nil
+	for _, sub := range it.its {
+		if it.Node == nil || it.compare(sub.Key, it.Node.Key) < 0 {
+			it.Node = sub.Node
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package treap_test
+
+import (
+	"testing"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharded(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		new  func() *treap.Sharded
+	}{{
+		desc: "Hash",
+		new: func() *treap.Sharded {
+			return treap.NewHashSharded(handle, 8, hashInt)
+		},
+	}, {
+		desc: "Range",
+		new: func() *treap.Sharded {
+			return treap.NewRangeSharded(handle, 25, 50, 75)
+		},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			s := tc.new()
+			cs := mkTestCases(100)
+
+			for _, tc := range cs {
+				require.True(t, s.Upsert(tc.key, tc.value, tc.weight))
+			}
+			assert.Equal(t, len(cs), s.Len())
+
+			t.Run("Update", func(t *testing.T) {
+				assert.False(t, s.Upsert(cs[0].key, cs[0].value, cs[0].weight))
+				assert.Equal(t, len(cs), s.Len())
+			})
+
+			t.Run("Get", func(t *testing.T) {
+				for _, tc := range cs {
+					v, ok := s.Get(tc.key)
+					assert.True(t, ok)
+					assert.Equal(t, tc.value, v)
+				}
+			})
+
+			t.Run("Iter", func(t *testing.T) {
+				var keys []int
+				for it := s.Iter(); it.Node != nil; it.Next() {
+					keys = append(keys, it.Key.(int))
+				}
+
+				require.Len(t, keys, len(cs))
+				for i, k := range keys {
+					assert.Equal(t, i, k, "iterator should traverse in key order")
+				}
+			})
+
+			t.Run("Delete", func(t *testing.T) {
+				assert.False(t, s.Delete(-1))
+				assert.True(t, s.Delete(cs[0].key))
+				assert.Equal(t, len(cs)-1, s.Len())
+
+				_, ok := s.Get(cs[0].key)
+				assert.False(t, ok)
+			})
+
+			t.Run("Pop", func(t *testing.T) {
+				var (
+					prev = -1
+					n    int
+				)
+
+				for node, ok := s.Pop(); ok; node, ok = s.Pop() {
+					assert.LessOrEqual(t, prev, node.Weight.(int),
+						"pop should return entries in weight order")
+					prev = node.Weight.(int)
+					n++
+				}
+
+				assert.Equal(t, len(cs)-1, n)
+				assert.Zero(t, s.Len())
+			})
+		})
+	}
+}
+
+func TestNewHashSharded_InvalidCount(t *testing.T) {
+	t.Parallel()
+
+	for _, n := range []int{0, -1} {
+		assert.Panics(t, func() { treap.NewHashSharded(handle, n, hashInt) }, "n=%d", n)
+	}
+}
+
+func hashInt(key interface{}) uint64 {
+	return uint64(key.(int)) * 0x9E3779B97F4A7C15
+}
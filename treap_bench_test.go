@@ -1,6 +1,7 @@
 package treap_test
 
 import (
+	"math/rand"
 	"testing"
 
 	"github.com/lthibault/treap"
@@ -119,6 +120,42 @@ func BenchmarkIterSync(b *testing.B) {
 	}
 }
 
+func BenchmarkUpsertParallel(b *testing.B) {
+	b.Run("CAS", func(b *testing.B) {
+		a := treap.NewAtomic(handle)
+		a.Update(func(*treap.Node) *treap.Node { return newPrefilledTreap(handle, 1000) })
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				// random weights keep the treap balanced
+				w := rand.Int()
+				a.Update(func(n *treap.Node) *treap.Node {
+					n, _ = handle.Upsert(n, i%1000, i, w)
+					return n
+				})
+			}
+		})
+	})
+
+	b.Run("Combiner", func(b *testing.B) {
+		a := treap.NewAtomic(handle)
+		a.Update(func(*treap.Node) *treap.Node { return newPrefilledTreap(handle, 1000) })
+		c := treap.NewCombiner(a)
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		b.RunParallel(func(pb *testing.PB) {
+			for i := 0; pb.Next(); i++ {
+				c.Upsert(i%1000, i, rand.Int())
+			}
+		})
+	})
+}
+
 func newPrefilledTreap(handle treap.Handle, n int) *treap.Node {
 	var root *treap.Node
 	cs := mkTestCases(n)
@@ -4,6 +4,7 @@
 package treap_test
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -76,3 +77,205 @@ func TestRace(t *testing.T) {
 func getRune(i int) rune {
 	return rune(chars[i%(len(chars)-1)])
 }
+
+func TestRaceSharded(t *testing.T) {
+	s := treap.NewHashSharded(handle, 4, hashInt)
+
+	var wg sync.WaitGroup
+	wg.Add(len(chars))
+
+	ch := make(chan struct{})
+
+	for i := 0; i < len(chars); i++ {
+		go func(k int, val rune) {
+			defer wg.Done()
+
+			<-ch // try to get as many read/writes happening at the same time
+
+			for i := 0; i < 1000; i++ {
+				switch {
+				case i&k == 0:
+					s.Upsert(k, val, k)
+				case i&k == k-1:
+					s.Delete(k)
+				case i%7 == 0:
+					s.Pop()
+				default:
+					if v, ok := s.Get(k); ok && v.(rune) != val {
+						t.Error("violation")
+					}
+				}
+			}
+		}(i, getRune(i))
+	}
+
+	close(ch)
+	wg.Wait()
+}
+
+func TestRaceGroup(t *testing.T) {
+	g := treap.NewGroup(handle, handle)
+
+	var wg sync.WaitGroup
+	wg.Add(len(chars))
+
+	ch := make(chan struct{})
+
+	for i := 0; i < len(chars); i++ {
+		go func(k int) {
+			defer wg.Done()
+
+			<-ch // try to get as many read/writes happening at the same time
+
+			for i := 0; i < 1000; i++ {
+				switch {
+				case i&k == 0:
+					g.Update(func(tx *treap.GroupTxn) error {
+						tx.Upsert(0, k, k, k)
+						tx.Upsert(1, k, k, k)
+						return nil
+					})
+				case i&k == k-1:
+					g.Update(func(tx *treap.GroupTxn) error {
+						tx.Delete(0, k)
+						tx.Delete(1, k)
+						return nil
+					})
+				default:
+					// both treaps must always agree
+					roots := g.Snapshot()
+					_, ok0 := handle.Get(roots[0], k)
+					_, ok1 := handle.Get(roots[1], k)
+					if ok0 != ok1 {
+						t.Error("violation")
+					}
+				}
+			}
+		}(i)
+	}
+
+	close(ch)
+	wg.Wait()
+}
+
+func TestRaceTxn(t *testing.T) {
+	a := treap.NewAtomic(handle)
+
+	var wg sync.WaitGroup
+	wg.Add(len(chars))
+
+	ch := make(chan struct{})
+
+	for i := 0; i < len(chars); i++ {
+		go func(k int) {
+			defer wg.Done()
+
+			<-ch // try to get as many read/writes happening at the same time
+
+			for i := 0; i < 100; i++ {
+				// increment a shared counter, and a per-goroutine counter
+				a.Txn(func(tx *treap.Txn) error {
+					n, _ := tx.Get(-1)
+					if n == nil {
+						n = 0
+					}
+					tx.Upsert(-1, n.(int)+1, 0)
+
+					m, _ := tx.Get(k)
+					if m == nil {
+						m = 0
+					}
+					tx.Upsert(k, m.(int)+1, k)
+					return nil
+				})
+			}
+		}(i)
+	}
+
+	close(ch)
+	wg.Wait()
+
+	if v, _ := handle.Get(a.Load(), -1); v != len(chars)*100 {
+		t.Errorf("lost update: counter is %v", v)
+	}
+}
+
+func TestRaceCombiner(t *testing.T) {
+	a := treap.NewAtomic(handle)
+	c := treap.NewCombiner(a)
+
+	var wg sync.WaitGroup
+	wg.Add(len(chars))
+
+	ch := make(chan struct{})
+
+	for i := 0; i < len(chars); i++ {
+		go func(k int, val rune) {
+			defer wg.Done()
+
+			<-ch // try to get as many read/writes happening at the same time
+
+			for i := 0; i < 1000; i++ {
+				switch {
+				case i&k == 0:
+					c.Upsert(k, val, k)
+				case i&k == k-1:
+					c.Delete(k)
+				case i%5 == 0:
+					// direct writes must not interfere with the combiner
+					a.Update(func(n *treap.Node) *treap.Node {
+						return handle.Delete(n, k)
+					})
+				default:
+					v, ok := handle.Get(a.Load(), k)
+					if ok && v.(rune) != val {
+						t.Error("violation")
+					}
+				}
+			}
+		}(i, getRune(i))
+	}
+
+	close(ch)
+	wg.Wait()
+}
+
+func TestRaceQueue(t *testing.T) {
+	q := treap.NewQueue(handle, 8)
+
+	var (
+		wg     sync.WaitGroup
+		popped int64
+	)
+
+	for i := 0; i < len(chars); i++ {
+		wg.Add(2)
+
+		go func(k int) {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				if err := q.PushWait(context.Background(), k*100+i, i, i); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				if _, err := q.PopWait(context.Background()); err != nil {
+					t.Error(err)
+				}
+				atomic.AddInt64(&popped, 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if popped != int64(len(chars)*100) || q.Len() != 0 {
+		t.Errorf("popped %d items, %d remaining", popped, q.Len())
+	}
+}
@@ -1,6 +1,7 @@
 package treap_test
 
 import (
+	"math"
 	"math/rand" // don't seed; keep reproducible.
 	"testing"
 
@@ -244,6 +245,62 @@ func TestSetWeight(t *testing.T) {
 	}
 }
 
+func TestIncreaseWeight(t *testing.T) {
+	t.Parallel()
+
+	for _, tt := range []struct {
+		desc   string
+		update func(n *treap.Node, key, weight interface{}) *treap.Node
+	}{
+		{"SetWeight", func(n *treap.Node, key, weight interface{}) *treap.Node {
+			n, _ = handle.SetWeight(n, key, weight)
+			return n
+		}},
+		{"Upsert", func(n *treap.Node, key, weight interface{}) *treap.Node {
+			n, _ = handle.Upsert(n, key, "updated", weight)
+			return n
+		}},
+	} {
+		root := newPrefilledTreap(handle, 1000)
+		key := root.Key
+
+		// the root must sink all the way to the bottom of the heap
+		root = tt.update(root, key, math.MaxInt)
+		assertHeapOrder(t, root)
+
+		// raise the weights of random keys, checking the heap after each update
+		for i := 0; i < 100; i++ {
+			k := rand.Intn(1000)
+			n, ok := handle.GetNode(root, k)
+			require.True(t, ok)
+
+			w := n.Weight.(int)
+			root = tt.update(root, k, w+(math.MaxInt-w)/2)
+			assertHeapOrder(t, root)
+		}
+
+		var n *treap.Node
+		for w := root.Weight; root != nil; _, root = handle.Pop(root) {
+			require.LessOrEqual(t, w, root.Weight, "heap property violated")
+			w, n = root.Weight, root
+		}
+
+		assert.Equal(t, key, n.Key, tt.desc)
+	}
+}
+
+// assertHeapOrder checks that no node has a lower weight than its parent.
+func assertHeapOrder(t *testing.T, n *treap.Node) {
+	t.Helper()
+
+	for _, child := range []*treap.Node{n.Left, n.Right} {
+		if child != nil {
+			require.LessOrEqual(t, n.Weight.(int), child.Weight.(int), "heap property violated")
+			assertHeapOrder(t, child)
+		}
+	}
+}
+
 func TestPop(t *testing.T) {
 	t.Parallel()
 	var root *treap.Node
@@ -265,6 +322,118 @@ func TestPop(t *testing.T) {
 	}
 }
 
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	var root *treap.Node
+	for _, tc := range mkTestCases(100) {
+		root, _ = handle.Insert(root, tc.key, tc.value, tc.weight)
+	}
+
+	left, right := handle.Split(root, 50)
+
+	_, ok := handle.Get(left, 50)
+	assert.False(t, ok, "split key should not be present in left subtreap")
+	_, ok = handle.Get(right, 50)
+	assert.False(t, ok, "split key should not be present in right subtreap")
+
+	for i := 0; i < 50; i++ {
+		_, ok = handle.Get(left, i)
+		assert.True(t, ok, "key %d should be in left subtreap", i)
+	}
+
+	for i := 51; i < 100; i++ {
+		_, ok = handle.Get(right, i)
+		assert.True(t, ok, "key %d should be in right subtreap", i)
+	}
+
+	_, ok = handle.Get(root, 50)
+	assert.True(t, ok, "split should not modify the original treap")
+}
+
+func TestNilWeights(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		comp treap.Comparator
+	}{{
+		desc: "NilLast",
+		comp: treap.NilLast(treap.IntComparator),
+	}, {
+		desc: "NilRejected",
+		comp: treap.NilRejected(treap.IntComparator),
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			h := treap.Handle{
+				CompareKeys:    treap.IntComparator,
+				CompareWeights: tc.comp,
+			}
+
+			var root *treap.Node
+			cs := mkTestCases(100)
+			for _, tc := range cs {
+				root, _ = h.Insert(root, tc.key, tc.value, tc.weight)
+			}
+
+			require.NotPanics(t, func() {
+				for _, tc := range cs[:50] {
+					root = h.Delete(root, tc.key)
+				}
+			})
+
+			testOthers(t, h, root, cs[50:])
+		})
+	}
+
+	t.Run("NilLastPopsLast", func(t *testing.T) {
+		h := treap.Handle{
+			CompareKeys:    treap.IntComparator,
+			CompareWeights: treap.NilLast(treap.IntComparator),
+		}
+
+		var root *treap.Node
+		root, _ = h.Insert(root, 1, "no deadline", nil)
+		root, _ = h.Insert(root, 2, "deadline", 100)
+
+		v, root := h.Pop(root)
+		assert.Equal(t, "deadline", v)
+		v, _ = h.Pop(root)
+		assert.Equal(t, "no deadline", v)
+	})
+}
+
+func TestPopWhile(t *testing.T) {
+	t.Parallel()
+
+	var root *treap.Node
+	for _, tc := range mkTestCases(100) {
+		root, _ = handle.Insert(root, tc.key, tc.value, tc.weight)
+	}
+
+	// pop the lowest-weighted half
+	var popped []int
+	tail := handle.PopWhile(root, func(n *treap.Node) bool {
+		if len(popped) == 50 {
+			return false
+		}
+
+		popped = append(popped, n.Weight.(int))
+		return true
+	})
+
+	require.Len(t, popped, 50)
+	for i := 1; i < len(popped); i++ {
+		assert.LessOrEqual(t, popped[i-1], popped[i], "heap property violated")
+	}
+
+	require.NotNil(t, tail)
+	assert.LessOrEqual(t, popped[49], tail.Weight.(int))
+
+	assert.Nil(t, handle.PopWhile(tail, func(*treap.Node) bool { return true }))
+	assert.Nil(t, handle.PopWhile(nil, func(*treap.Node) bool { return true }))
+}
+
 func TestFuzz(t *testing.T) {
 	t.Parallel()
 	/*
@@ -0,0 +1,89 @@
+package treap
+
+import "time"
+
+// TTLCache is a thread-safe key-value cache whose entries expire after a time-to-live.
+// Entries are looked up using the treap's key ordering, and evicted in order of expiry
+// using its heap ordering.
+//
+// Expiration is both lazy and active:  Get reports a miss for expired entries, and a
+// background timer sweeps expired entries from the cache as soon as they are due.
+// Reads are wait-free.
+type TTLCache struct {
+	s     *Scheduler
+	clock Clock
+	ttl   time.Duration
+}
+
+// NewTTLCache returns a cache whose keys are ordered by compareKeys, and whose entries
+// expire after ttl by default.  If onEvict is non-nil, it is called for each entry
+// that is swept after expiring, from the timer's goroutine, in order of expiry.  Calls
+// to onEvict never overlap.  It is not called for entries removed by Delete.  If clock is nil, SystemClock is used.
+func NewTTLCache(compareKeys Comparator, ttl time.Duration, clock Clock, onEvict func(key, value interface{})) *TTLCache {
+	if clock == nil {
+		clock = SystemClock
+	}
+
+	if onEvict == nil {
+		onEvict = func(key, value interface{}) {}
+	}
+
+	return &TTLCache{
+		s:     NewScheduler(compareKeys, clock, onEvict),
+		clock: clock,
+		ttl:   ttl,
+	}
+}
+
+// Get a value by key.  Returns false if the key is absent or has expired.
+func (c *TTLCache) Get(key interface{}) (interface{}, bool) {
+	n, ok := c.s.h.GetNode(c.s.Snapshot(), key)
+	if !ok || !c.clock.Now().Before(n.Weight.(time.Time)) {
+		return nil, false
+	}
+
+	return n.Value, true
+}
+
+// Expiry returns the time at which the specified key expires.  Returns false if the
+// key is absent or has expired.
+func (c *TTLCache) Expiry(key interface{}) (time.Time, bool) {
+	at, ok := c.s.Deadline(key)
+	if !ok || !c.clock.Now().Before(at) {
+		return time.Time{}, false
+	}
+
+	return at, true
+}
+
+// Set a value with the cache's default TTL.  Returns false if an existing entry was
+// replaced.
+func (c *TTLCache) Set(key, value interface{}) bool {
+	return c.SetTTL(key, value, c.ttl)
+}
+
+// SetTTL sets a value that expires after the specified TTL.  Returns false if an
+// existing entry was replaced.
+func (c *TTLCache) SetTTL(key, value interface{}, ttl time.Duration) bool {
+	return c.s.Schedule(key, value, c.clock.Now().Add(ttl))
+}
+
+// Touch resets the TTL of the specified key to the cache's default.  Returns false if
+// the key is absent or has expired.
+func (c *TTLCache) Touch(key interface{}) bool {
+	if _, ok := c.Expiry(key); !ok {
+		return false
+	}
+
+	return c.s.Reschedule(key, c.clock.Now().Add(c.ttl))
+}
+
+// Delete an entry.  Returns false if the key was absent.
+func (c *TTLCache) Delete(key interface{}) bool {
+	return c.s.Cancel(key)
+}
+
+// Close stops the background timer.  Expired entries will no longer be swept.
+func (c *TTLCache) Close() {
+	c.s.Stop()
+}
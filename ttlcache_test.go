@@ -0,0 +1,81 @@
+package treap_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLCache(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	evicted := map[interface{}]interface{}{}
+	c := treap.NewTTLCache(treap.StringComparator, time.Minute, clock, func(key, value interface{}) {
+		evicted[key] = value
+	})
+	defer c.Close()
+
+	assert.True(t, c.Set("a", 1))
+	assert.True(t, c.SetTTL("b", 2, time.Second))
+	assert.True(t, c.SetTTL("c", 3, 2*time.Minute))
+	assert.False(t, c.Set("a", 10), "existing entry should be replaced")
+
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 10, v)
+
+	_, ok = c.Get("missing")
+	assert.False(t, ok)
+
+	t.Run("Expire", func(t *testing.T) {
+		clock.Advance(time.Second)
+
+		_, ok := c.Get("b")
+		assert.False(t, ok, "expired entry should miss")
+		assert.Equal(t, 2, evicted["b"], "expired entry should be evicted")
+
+		_, ok = c.Expiry("b")
+		assert.False(t, ok)
+	})
+
+	t.Run("Touch", func(t *testing.T) {
+		clock.Advance(30 * time.Second)
+		assert.True(t, c.Touch("a"))
+		assert.False(t, c.Touch("b"))
+
+		at, ok := c.Expiry("a")
+		assert.True(t, ok)
+		assert.Equal(t, clock.Now().Add(time.Minute), at)
+
+		clock.Advance(45 * time.Second)
+		_, ok = c.Get("a")
+		assert.True(t, ok, "touched entry should not have expired")
+		assert.NotContains(t, evicted, "a")
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.True(t, c.Delete("c"))
+		assert.False(t, c.Delete("c"))
+
+		clock.Advance(time.Hour)
+		assert.NotContains(t, evicted, "c", "deleted entry should not be evicted")
+		assert.Equal(t, 10, evicted["a"])
+	})
+}
+
+func TestTTLCache_LazyExpiration(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	c := treap.NewTTLCache(treap.StringComparator, time.Minute, clock, nil)
+	c.Close() // disable active expiration
+
+	c.Set("a", 1)
+	clock.Advance(time.Minute)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok, "expired entry should miss even if it was not swept")
+}
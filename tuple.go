@@ -0,0 +1,305 @@
+package treap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"unsafe"
+)
+
+// ErrInvalidTuple is returned when decoding a malformed tuple.
+var ErrInvalidTuple = errors.New("treap: invalid tuple encoding")
+
+// Element tags.  The order of the tags determines the order of elements of different
+// types.  Note that nil sorts first, consistently with the built-in comparators.
+const (
+	tagNil byte = iota
+	tagBytes
+	tagString
+	tagInt
+	tagUint
+	tagFloat
+	tagTime
+)
+
+// Integer and float kinds are encoded after the value, so that they do not affect
+// ordering between values of different widths.
+const (
+	kindInt byte = iota
+	kindInt8
+	kindInt16
+	kindInt32
+	kindInt64
+)
+
+const (
+	kindUint byte = iota
+	kindUint8
+	kindUint16
+	kindUint32
+	kindUint64
+)
+
+const (
+	kindFloat32 byte = iota
+	kindFloat64
+)
+
+// Tuple is a composite key whose encoding preserves order.  Encoded tuples compare
+// bytewise (see BytesComparator) in the same order as the tuples themselves, i.e.
+// element by element, with shorter tuples ordered before longer tuples that share the
+// same prefix.  This allows complex keys to use the fast bytewise comparison path.
+//
+// Supported element types are nil, []byte, string, signed and unsigned integers of any
+// width, float32, float64 and time.Time.  Signed integers of different widths are
+// ordered by value, as are unsigned integers and floats.  Negative zero is encoded,
+// and therefore decoded, as positive zero, since the two are equal.  NaN is unordered,
+// and cannot be encoded.  Otherwise, elements of different kinds are ordered as
+// follows:
+//
+//	nil < []byte < string < signed int < unsigned int < float < time.Time
+type Tuple []interface{}
+
+// Encode the tuple into an order-preserving []byte key.
+func (t Tuple) Encode() ([]byte, error) {
+	return t.AppendEncoded(nil)
+}
+
+// AppendEncoded appends the encoded tuple to b and returns the extended buffer.
+func (t Tuple) AppendEncoded(b []byte) (_ []byte, err error) {
+	for _, v := range t {
+		switch v := v.(type) {
+		case nil:
+			b = append(b, tagNil)
+		case []byte:
+			b = appendEscaped(append(b, tagBytes), *(*string)(unsafe.Pointer(&v)))
+		case string:
+			b = appendEscaped(append(b, tagString), v)
+		case int:
+			b = appendInt(b, int64(v), kindInt)
+		case int8:
+			b = appendInt(b, int64(v), kindInt8)
+		case int16:
+			b = appendInt(b, int64(v), kindInt16)
+		case int32:
+			b = appendInt(b, int64(v), kindInt32)
+		case int64:
+			b = appendInt(b, v, kindInt64)
+		case uint:
+			b = appendUint(b, uint64(v), kindUint)
+		case uint8:
+			b = appendUint(b, uint64(v), kindUint8)
+		case uint16:
+			b = appendUint(b, uint64(v), kindUint16)
+		case uint32:
+			b = appendUint(b, uint64(v), kindUint32)
+		case uint64:
+			b = appendUint(b, v, kindUint64)
+		case float32:
+			if b, err = appendFloat(b, float64(v), kindFloat32); err != nil {
+				return nil, err
+			}
+		case float64:
+			if b, err = appendFloat(b, v, kindFloat64); err != nil {
+				return nil, err
+			}
+		case time.Time:
+			b = append(b, tagTime)
+			b = appendUint64(b, uint64(v.Unix())^1<<63)
+			b = appendUint32(b, uint32(v.Nanosecond()))
+		default:
+			return nil, fmt.Errorf("treap: cannot encode %T in tuple", v)
+		}
+	}
+
+	return b, nil
+}
+
+// DecodeTuple decodes a tuple produced by Tuple.Encode.  Elements are decoded to their
+// original types, except time.Time values, which are decoded in UTC.
+func DecodeTuple(b []byte) (Tuple, error) {
+	var t Tuple
+	for len(b) > 0 {
+		tag := b[0]
+		b = b[1:]
+
+		switch tag {
+		case tagNil:
+			t = append(t, nil)
+
+		case tagBytes, tagString:
+			v, rest, err := readEscaped(b)
+			if err != nil {
+				return nil, err
+			}
+
+			if b = rest; tag == tagString {
+				t = append(t, string(v))
+			} else {
+				t = append(t, v)
+			}
+
+		case tagInt:
+			if len(b) < 9 {
+				return nil, ErrInvalidTuple
+			}
+
+			v := int64(binary.BigEndian.Uint64(b) ^ 1<<63)
+			switch kind := b[8]; kind {
+			case kindInt:
+				t = append(t, int(v))
+			case kindInt8:
+				t = append(t, int8(v))
+			case kindInt16:
+				t = append(t, int16(v))
+			case kindInt32:
+				t = append(t, int32(v))
+			case kindInt64:
+				t = append(t, v)
+			default:
+				return nil, ErrInvalidTuple
+			}
+
+			b = b[9:]
+
+		case tagUint:
+			if len(b) < 9 {
+				return nil, ErrInvalidTuple
+			}
+
+			v := binary.BigEndian.Uint64(b)
+			switch kind := b[8]; kind {
+			case kindUint:
+				t = append(t, uint(v))
+			case kindUint8:
+				t = append(t, uint8(v))
+			case kindUint16:
+				t = append(t, uint16(v))
+			case kindUint32:
+				t = append(t, uint32(v))
+			case kindUint64:
+				t = append(t, v)
+			default:
+				return nil, ErrInvalidTuple
+			}
+
+			b = b[9:]
+
+		case tagFloat:
+			if len(b) < 9 {
+				return nil, ErrInvalidTuple
+			}
+
+			bits := binary.BigEndian.Uint64(b)
+			if bits&(1<<63) != 0 {
+				bits ^= 1 << 63 // positive
+			} else {
+				bits = ^bits // negative
+			}
+
+			switch kind := b[8]; kind {
+			case kindFloat32:
+				t = append(t, float32(math.Float64frombits(bits)))
+			case kindFloat64:
+				t = append(t, math.Float64frombits(bits))
+			default:
+				return nil, ErrInvalidTuple
+			}
+
+			b = b[9:]
+
+		case tagTime:
+			if len(b) < 12 {
+				return nil, ErrInvalidTuple
+			}
+
+			sec := int64(binary.BigEndian.Uint64(b) ^ 1<<63)
+			nsec := int64(binary.BigEndian.Uint32(b[8:]))
+			t = append(t, time.Unix(sec, nsec).UTC())
+			b = b[12:]
+
+		default:
+			return nil, ErrInvalidTuple
+		}
+	}
+
+	return t, nil
+}
+
+// appendEscaped appends a null-terminated string or byte slice to b.  Null bytes
+// within s are escaped as 0x00 0xFF, which preserves ordering.
+func appendEscaped(b []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		if b = append(b, s[i]); s[i] == 0x00 {
+			b = append(b, 0xFF)
+		}
+	}
+
+	return append(b, 0x00)
+}
+
+func readEscaped(b []byte) (v, rest []byte, err error) {
+	v = make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] != 0x00 {
+			v = append(v, b[i])
+			continue
+		}
+
+		if i+1 < len(b) && b[i+1] == 0xFF {
+			v = append(v, 0x00)
+			i++
+			continue
+		}
+
+		return v, b[i+1:], nil
+	}
+
+	return nil, nil, ErrInvalidTuple
+}
+
+func appendInt(b []byte, v int64, kind byte) []byte {
+	b = appendUint64(append(b, tagInt), uint64(v)^1<<63)
+	return append(b, kind)
+}
+
+func appendUint(b []byte, v uint64, kind byte) []byte {
+	b = appendUint64(append(b, tagUint), v)
+	return append(b, kind)
+}
+
+// appendFloat encodes v such that its bytewise order matches its numeric order.
+// Positive floats have their sign bit flipped; negative floats have all bits flipped.
+func appendFloat(b []byte, v float64, kind byte) ([]byte, error) {
+	if math.IsNaN(v) {
+		return nil, errors.New("treap: cannot encode NaN in tuple")
+	}
+
+	if v == 0 {
+		v = 0 // canonicalize -0, which would otherwise sort below +0
+	}
+
+	bits := math.Float64bits(v)
+	if bits&(1<<63) != 0 {
+		bits = ^bits
+	} else {
+		bits ^= 1 << 63
+	}
+
+	b = appendUint64(append(b, tagFloat), bits)
+	return append(b, kind), nil
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
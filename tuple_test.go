@@ -0,0 +1,147 @@
+package treap_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTuple_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	tuple := treap.Tuple{
+		nil,
+		[]byte("by\x00tes"),
+		"str\x00ing",
+		"",
+		int(-1), int8(-8), int16(16), int32(-32), int64(math.MinInt64),
+		uint(1), uint8(8), uint16(16), uint32(32), uint64(math.MaxUint64),
+		float32(-1.5), math.Inf(-1), 0.0, math.Inf(1),
+		time.Date(1969, 7, 20, 20, 17, 40, 42, time.UTC),
+	}
+
+	b, err := tuple.Encode()
+	require.NoError(t, err)
+
+	got, err := treap.DecodeTuple(b)
+	require.NoError(t, err)
+	assert.Equal(t, tuple, got)
+}
+
+func TestTuple_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	_, err := treap.Tuple{struct{}{}}.Encode()
+	assert.Error(t, err)
+}
+
+func TestTuple_Float(t *testing.T) {
+	t.Parallel()
+
+	pos, err := treap.Tuple{0.0}.Encode()
+	require.NoError(t, err)
+
+	neg, err := treap.Tuple{math.Copysign(0, -1)}.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, pos, neg, "-0 and +0 are equal, and should encode identically")
+
+	neg32, err := treap.Tuple{float32(math.Copysign(0, -1))}.Encode()
+	require.NoError(t, err)
+	got, err := treap.DecodeTuple(neg32)
+	require.NoError(t, err)
+	assert.False(t, math.Signbit(float64(got[0].(float32))), "-0 should decode as +0")
+
+	for _, nan := range []interface{}{
+		math.NaN(),
+		math.Copysign(math.NaN(), -1),
+		float32(math.NaN()),
+	} {
+		_, err = treap.Tuple{"a", nan}.Encode()
+		assert.Error(t, err, "NaN should be rejected")
+	}
+}
+
+func TestTuple_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		desc string
+		b    []byte
+	}{{
+		desc: "unknown tag",
+		b:    []byte{0xFF},
+	}, {
+		desc: "unterminated string",
+		b:    []byte{0x02, 'a'},
+	}, {
+		desc: "truncated int",
+		b:    []byte{0x03, 0x80, 0x00},
+	}} {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := treap.DecodeTuple(tc.b)
+			assert.ErrorIs(t, err, treap.ErrInvalidTuple)
+		})
+	}
+}
+
+func TestTuple_Order(t *testing.T) {
+	t.Parallel()
+
+	/*
+		Encode random (string, int, float64) tuples of varying lengths, and ensure the
+		bytewise order of the encodings matches the element-wise order of the tuples.
+	*/
+
+	type entry struct {
+		tuple treap.Tuple
+		key   []byte
+	}
+
+	rng := rand.New(rand.NewSource(42))
+	es := make([]entry, 500)
+	for i := range es {
+		full := treap.Tuple{
+			randStr(rng.Intn(3)) + string([]byte{byte(rng.Intn(2))}),
+			rng.Intn(20) - 10,
+			rng.NormFloat64(),
+		}
+
+		es[i].tuple = full[:rng.Intn(len(full)+1)]
+
+		var err error
+		es[i].key, err = es[i].tuple.Encode()
+		require.NoError(t, err)
+	}
+
+	sort.Slice(es, func(i, j int) bool {
+		return treap.BytesComparator(es[i].key, es[j].key) < 0
+	})
+
+	comps := []treap.Comparator{
+		treap.StringComparator,
+		treap.IntComparator,
+		treap.Float64Comparator,
+	}
+
+	for i := 1; i < len(es); i++ {
+		a, b := es[i-1].tuple, es[i].tuple
+		assert.LessOrEqual(t, compareTuples(comps, a, b), 0,
+			"%v should not sort after %v", a, b)
+	}
+}
+
+func compareTuples(comps []treap.Comparator, a, b treap.Tuple) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := comps[i](a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+
+	return len(a) - len(b)
+}
@@ -0,0 +1,172 @@
+package treap
+
+// Txn is an optimistic transaction over an Atomic treap.  It records the keys it
+// reads and the writes it performs.  If the transaction fails to commit because the
+// root was concurrently replaced, its writes are rebased onto the new root, provided
+// none of the keys it read have changed.  The transaction function is only re-run in
+// the event of a true conflict.
+//
+// Changes to a key are detected by comparing the identity of the node that holds the
+// key (or the absence thereof) in the old and new roots.  This is cheap, but
+// conservative:  because treaps are updated by path-copying, a concurrent write to a
+// key in the subtree below a node that was read also counts as a conflict.
+//
+// A Txn is NOT thread-safe, and MUST NOT be used after the function passed to
+// Atomic.Txn has returned.
+type Txn struct {
+	h          Handle
+	base, root *Node
+	reads      []txnRead
+	writes     []txnWrite
+}
+
+type txnRead struct {
+	key  interface{}
+	node *Node // node holding the key in the base treap, or nil
+}
+
+type txnOp uint8
+
+const (
+	txnUpsert txnOp = iota
+	txnSetWeight
+	txnDelete
+)
+
+type txnWrite struct {
+	op               txnOp
+	key, val, weight interface{}
+}
+
+// Txn runs f in an optimistic transaction and commits its writes atomically.  If
+// another writer replaced the root in the meantime, the transaction's writes are
+// replayed onto the new root, unless a key read by the transaction has changed, in
+// which case f is called again on a fresh snapshot.  As such, f SHOULD NOT have
+// side-effects.
+//
+// If f returns an error, the transaction is aborted and the error is returned.
+func (a *Atomic) Txn(f func(*Txn) error) error {
+	for {
+		tx := &Txn{h: a.h}
+		tx.base = a.Load()
+		tx.root = tx.base
+
+		if err := f(tx); err != nil {
+			return err
+		}
+
+		if tx.commit(a) {
+			return nil
+		}
+	}
+}
+
+// Get an element by key.
+func (tx *Txn) Get(key interface{}) (interface{}, bool) {
+	tx.read(key)
+	return tx.h.Get(tx.root, key)
+}
+
+// Insert an element, returning false if it is already present.
+func (tx *Txn) Insert(key, val, weight interface{}) (ok bool) {
+	tx.read(key)
+
+	var root *Node
+	if root, ok = tx.h.Insert(tx.root, key, val, weight); ok {
+		tx.write(txnUpsert, key, val, weight, root)
+	}
+
+	return
+}
+
+// Upsert updates an element, creating one if it is missing.
+func (tx *Txn) Upsert(key, val, weight interface{}) (created bool) {
+	tx.read(key)
+
+	var root *Node
+	root, created = tx.h.Upsert(tx.root, key, val, weight)
+	tx.write(txnUpsert, key, val, weight, root)
+	return
+}
+
+// SetWeight adjusts the weight of an element, returning false if it is not present.
+func (tx *Txn) SetWeight(key, weight interface{}) (ok bool) {
+	tx.read(key)
+
+	var root *Node
+	if root, ok = tx.h.SetWeight(tx.root, key, weight); ok {
+		tx.write(txnSetWeight, key, nil, weight, root)
+	}
+
+	return
+}
+
+// Delete an element.  Deletion is a blind write; it does not count as a read.
+func (tx *Txn) Delete(key interface{}) {
+	tx.write(txnDelete, key, nil, nil, tx.h.Delete(tx.root, key))
+}
+
+// read records the node holding key in the base treap, unless the key has already
+// been written by the transaction, in which case its value no longer depends on the
+// base treap.
+func (tx *Txn) read(key interface{}) {
+	for _, w := range tx.writes {
+		if tx.h.CompareKeys(key, w.key) == 0 {
+			return
+		}
+	}
+
+	n, _ := tx.h.GetNode(tx.base, key)
+	tx.reads = append(tx.reads, txnRead{key: key, node: n})
+}
+
+func (tx *Txn) write(op txnOp, key, val, weight interface{}, root *Node) {
+	tx.root = root
+	tx.writes = append(tx.writes, txnWrite{
+		op:     op,
+		key:    key,
+		val:    val,
+		weight: weight,
+	})
+}
+
+// commit the transaction to a, rebasing as needed.  Returns false if the transaction
+// must be re-run.
+func (tx *Txn) commit(a *Atomic) bool {
+	if len(tx.writes) == 0 {
+		return true // read-only
+	}
+
+	for !a.CompareAndSwap(tx.base, tx.root) {
+		if !tx.rebase(a.Load()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rebase the transaction's writes onto base.  Returns false if any of the keys read by
+// the transaction have changed.
+func (tx *Txn) rebase(base *Node) bool {
+	for _, r := range tx.reads {
+		if n, _ := tx.h.GetNode(base, r.key); n != r.node {
+			return false
+		}
+	}
+
+	root := base
+	for _, w := range tx.writes {
+		switch w.op {
+		case txnUpsert:
+			root, _ = tx.h.Upsert(root, w.key, w.val, w.weight)
+		case txnSetWeight:
+			root, _ = tx.h.SetWeight(root, w.key, w.weight)
+		case txnDelete:
+			root = tx.h.Delete(root, w.key)
+		}
+	}
+
+	tx.base, tx.root = base, root
+	return true
+}
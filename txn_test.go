@@ -0,0 +1,156 @@
+package treap_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxn(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Commit", func(t *testing.T) {
+		a := treap.NewAtomic(handle)
+
+		err := a.Txn(func(tx *treap.Txn) error {
+			assert.True(t, tx.Insert(1, "a", 1))
+			assert.False(t, tx.Insert(1, "a", 1))
+			assert.True(t, tx.Upsert(2, "b", 2))
+			assert.True(t, tx.SetWeight(2, 0))
+			assert.False(t, tx.SetWeight(3, 0))
+
+			v, ok := tx.Get(1)
+			assert.True(t, ok)
+			assert.Equal(t, "a", v)
+
+			tx.Delete(1)
+			_, ok = tx.Get(1)
+			assert.False(t, ok)
+			return nil
+		})
+		require.NoError(t, err)
+
+		_, ok := handle.Get(a.Load(), 1)
+		assert.False(t, ok)
+
+		v, ok := handle.Get(a.Load(), 2)
+		assert.True(t, ok)
+		assert.Equal(t, "b", v)
+		assert.Equal(t, 0, a.Load().Weight)
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		a := treap.NewAtomic(handle)
+
+		errAbort := errors.New("abort")
+		err := a.Txn(func(tx *treap.Txn) error {
+			tx.Upsert(1, "a", 1)
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+		assert.Nil(t, a.Load())
+	})
+
+	/*
+		The remaining tests simulate a concurrent writer by committing directly to the
+		Atomic during the first run of the transaction function.  The treap is laid
+		out such that key 1 is a leaf, and is therefore unaffected by writes to key 3.
+
+			   2
+			  / \
+			 1   3
+	*/
+
+	setup := func() *treap.Atomic {
+		a := treap.NewAtomic(handle)
+		a.Update(func(n *treap.Node) *treap.Node {
+			n, _ = handle.Insert(n, 2, "b", 0)
+			n, _ = handle.Insert(n, 1, "a", 1)
+			n, _ = handle.Insert(n, 3, "c", 1)
+			return n
+		})
+		return a
+	}
+
+	t.Run("Rebase", func(t *testing.T) {
+		a := setup()
+
+		var runs int
+		err := a.Txn(func(tx *treap.Txn) error {
+			runs++
+
+			v, _ := tx.Get(1)
+			tx.Upsert(4, v, 4)
+
+			if runs == 1 { // concurrent write to an unrelated key
+				a.Update(func(n *treap.Node) *treap.Node {
+					n, _ = handle.Upsert(n, 3, "C", 1)
+					return n
+				})
+			}
+
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, runs, "transaction should have been rebased, not re-run")
+
+		v, _ := handle.Get(a.Load(), 3)
+		assert.Equal(t, "C", v, "concurrent write should be preserved")
+		v, _ = handle.Get(a.Load(), 4)
+		assert.Equal(t, "a", v, "transaction write should be committed")
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		a := setup()
+
+		var runs int
+		err := a.Txn(func(tx *treap.Txn) error {
+			runs++
+
+			v, _ := tx.Get(1)
+			tx.Upsert(4, v, 4)
+
+			if runs == 1 { // concurrent write to a key that was read
+				a.Update(func(n *treap.Node) *treap.Node {
+					n, _ = handle.Upsert(n, 1, "A", 1)
+					return n
+				})
+			}
+
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, runs, "transaction should have been re-run")
+
+		v, _ := handle.Get(a.Load(), 4)
+		assert.Equal(t, "A", v, "transaction should observe the concurrent write")
+	})
+
+	t.Run("BlindWrite", func(t *testing.T) {
+		a := setup()
+
+		var runs int
+		err := a.Txn(func(tx *treap.Txn) error {
+			runs++
+
+			tx.Delete(1)
+
+			if runs == 1 {
+				a.Update(func(n *treap.Node) *treap.Node {
+					n, _ = handle.Upsert(n, 1, "A", 1)
+					return n
+				})
+			}
+
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, runs, "blind writes should never conflict")
+
+		_, ok := handle.Get(a.Load(), 1)
+		assert.False(t, ok)
+	})
+}
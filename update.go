@@ -0,0 +1,201 @@
+package treap
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Update is a transformation of values and weights that is applied lazily to every
+// node in a key range.  See LazyHandle.UpdateRange.
+type Update interface {
+	// Apply returns the updated value and weight of a node.  Apply MUST NOT change
+	// the relative order of any two weights, as this would violate the heap property
+	// of the updated range.
+	Apply(value, weight interface{}) (interface{}, interface{})
+
+	// Compose returns an Update that is equivalent to applying the receiver, followed
+	// by next.
+	Compose(next Update) Update
+}
+
+// Offset is an Update that adds Delta to every weight, and leaves values unchanged.
+// Delta MUST have the same type as the weights, except for time.Time weights, for
+// which it MUST be a time.Duration.  Nil weights are unchanged.  Integer weights MUST
+// NOT overflow.
+type Offset struct {
+	Delta interface{}
+}
+
+// Apply the offset.
+func (o Offset) Apply(value, weight interface{}) (interface{}, interface{}) {
+	return value, addWeight(weight, o.Delta)
+}
+
+// Compose the offset with another update.
+func (o Offset) Compose(next Update) Update {
+	if n, ok := next.(Offset); ok {
+		return Offset{Delta: addWeight(o.Delta, n.Delta)}
+	}
+
+	return updates{o, next}
+}
+
+// Scale is an Update that multiplies every numeric or time.Duration weight by Factor,
+// and leaves values unchanged.  Integer weights are truncated after scaling, which may
+// cause distinct weights to compare equal.  Nil weights are unchanged.
+//
+// Apply panics if Factor is zero, negative or NaN, since it would reverse or erase the
+// order of weights, and if an integer weight overflows.
+type Scale struct {
+	Factor float64
+}
+
+// Apply the scale factor.
+func (s Scale) Apply(value, weight interface{}) (interface{}, interface{}) {
+	if !(s.Factor > 0) { // also catches NaN
+		panic(fmt.Sprintf("treap: invalid scale factor %v", s.Factor))
+	}
+
+	return value, scaleWeight(weight, s.Factor)
+}
+
+// Compose the scale factor with another update.
+func (s Scale) Compose(next Update) Update {
+	if n, ok := next.(Scale); ok {
+		return Scale{Factor: s.Factor * n.Factor}
+	}
+
+	return updates{s, next}
+}
+
+// updates is a sequence of updates that are applied in order.
+type updates []Update
+
+func (us updates) Apply(value, weight interface{}) (interface{}, interface{}) {
+	for _, u := range us {
+		value, weight = u.Apply(value, weight)
+	}
+
+	return value, weight
+}
+
+func (us updates) Compose(next Update) Update {
+	return append(us[:len(us):len(us)], next)
+}
+
+func addWeight(w, delta interface{}) interface{} {
+	switch w := w.(type) {
+	case nil:
+		return nil
+	case int:
+		return int(addInt(int64(w), int64(delta.(int)), bits.UintSize))
+	case int8:
+		return int8(addInt(int64(w), int64(delta.(int8)), 8))
+	case int16:
+		return int16(addInt(int64(w), int64(delta.(int16)), 16))
+	case int32:
+		return int32(addInt(int64(w), int64(delta.(int32)), 32))
+	case int64:
+		return int64(addInt(int64(w), int64(delta.(int64)), 64))
+	case uint:
+		return uint(addUint(uint64(w), uint64(delta.(uint)), bits.UintSize))
+	case uint8:
+		return uint8(addUint(uint64(w), uint64(delta.(uint8)), 8))
+	case uint16:
+		return uint16(addUint(uint64(w), uint64(delta.(uint16)), 16))
+	case uint32:
+		return uint32(addUint(uint64(w), uint64(delta.(uint32)), 32))
+	case uint64:
+		return uint64(addUint(uint64(w), uint64(delta.(uint64)), 64))
+	case float32:
+		return w + delta.(float32)
+	case float64:
+		return w + delta.(float64)
+	case time.Duration:
+		return time.Duration(addInt(int64(w), int64(delta.(time.Duration)), 64))
+	case time.Time:
+		return w.Add(delta.(time.Duration))
+	default:
+		panic(fmt.Sprintf("treap: cannot offset weight of type %T", w))
+	}
+}
+
+func scaleWeight(w interface{}, f float64) interface{} {
+	switch w := w.(type) {
+	case nil:
+		return nil
+	case int:
+		return int(scaleInt(int64(w), f, bits.UintSize))
+	case int8:
+		return int8(scaleInt(int64(w), f, 8))
+	case int16:
+		return int16(scaleInt(int64(w), f, 16))
+	case int32:
+		return int32(scaleInt(int64(w), f, 32))
+	case int64:
+		return int64(scaleInt(int64(w), f, 64))
+	case uint:
+		return uint(scaleUint(uint64(w), f, bits.UintSize))
+	case uint8:
+		return uint8(scaleUint(uint64(w), f, 8))
+	case uint16:
+		return uint16(scaleUint(uint64(w), f, 16))
+	case uint32:
+		return uint32(scaleUint(uint64(w), f, 32))
+	case uint64:
+		return uint64(scaleUint(uint64(w), f, 64))
+	case float32:
+		return float32(float64(w) * f)
+	case float64:
+		return w * f
+	case time.Duration:
+		return time.Duration(scaleInt(int64(w), f, 64))
+	default:
+		panic(fmt.Sprintf("treap: cannot scale weight of type %T", w))
+	}
+}
+
+// addInt returns w+d, and panics if the sum overflows a signed integer of the given
+// size.
+func addInt(w, d int64, size int) int64 {
+	sum := w + d
+	if (sum < w) != (d < 0) || sum < -1<<(size-1) || sum > 1<<(size-1)-1 {
+		panic("treap: weight overflow")
+	}
+
+	return sum
+}
+
+// addUint returns w+d, and panics if the sum overflows an unsigned integer of the given
+// size.
+func addUint(w, d uint64, size int) uint64 {
+	sum := w + d
+	if sum < w || size < 64 && sum >= 1<<size {
+		panic("treap: weight overflow")
+	}
+
+	return sum
+}
+
+// scaleInt returns w*f, truncated, and panics if the product overflows a signed integer
+// of the given size.
+func scaleInt(w int64, f float64, size int) int64 {
+	x := float64(w) * f
+	if lim := math.Ldexp(1, size-1); x < -lim || x >= lim {
+		panic("treap: weight overflow")
+	}
+
+	return int64(x)
+}
+
+// scaleUint returns w*f, truncated, and panics if the product overflows an unsigned
+// integer of the given size.
+func scaleUint(w uint64, f float64, size int) uint64 {
+	if float64(w)*f >= math.Ldexp(1, size) {
+		panic("treap: weight overflow")
+	}
+
+	return uint64(float64(w) * f)
+}
@@ -0,0 +1,350 @@
+package treap_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var lazyHandle = treap.LazyHandle(handle)
+
+// shift adds Value to int values, and Weight to int weights.
+type shift struct{ Value, Weight int }
+
+func (s shift) Apply(value, weight interface{}) (interface{}, interface{}) {
+	return value.(int) + s.Value, weight.(int) + s.Weight
+}
+
+func (s shift) Compose(next treap.Update) treap.Update {
+	n := next.(shift)
+	return shift{Value: s.Value + n.Value, Weight: s.Weight + n.Weight}
+}
+
+func TestLazyHandle(t *testing.T) {
+	t.Parallel()
+
+	var root *treap.LazyNode
+
+	root, ok := lazyHandle.Insert(root, 1, 10, 100)
+	require.True(t, ok)
+	_, ok = lazyHandle.Insert(root, 1, 11, 101)
+	assert.False(t, ok, "duplicate key should be rejected")
+
+	root, created := lazyHandle.Upsert(root, 1, 12, 102)
+	assert.False(t, created)
+	root, created = lazyHandle.Upsert(root, 2, 20, 50)
+	assert.True(t, created)
+
+	v, ok := lazyHandle.Get(root, 1)
+	assert.True(t, ok)
+	assert.Equal(t, 12, v)
+
+	root, ok = lazyHandle.SetWeight(root, 1, 10)
+	require.True(t, ok)
+	_, ok = lazyHandle.SetWeight(root, 3, 10)
+	assert.False(t, ok)
+
+	w, ok := lazyHandle.GetWeight(root, 1)
+	assert.True(t, ok)
+	assert.Equal(t, 10, w)
+	assert.Equal(t, 1, root.Key, "lightest node should be the root")
+	assert.Equal(t, 2, root.Right().Key)
+	assert.Nil(t, root.Left())
+
+	root = lazyHandle.Delete(root, 1)
+	_, ok = lazyHandle.Get(root, 1)
+	assert.False(t, ok)
+	assert.Equal(t, root, lazyHandle.Delete(root, 1), "deleting a missing key should be a nop")
+
+	v, root = lazyHandle.Pop(root)
+	assert.Equal(t, 20, v)
+	assert.Nil(t, root)
+}
+
+func TestUpdateRange(t *testing.T) {
+	t.Parallel()
+
+	var root *treap.LazyNode
+	for i := 0; i < 100; i++ {
+		root, _ = lazyHandle.Insert(root, i, i, rand.Intn(1000))
+	}
+
+	updated := lazyHandle.UpdateRange(root, 10, 20, treap.ClosedOpen, shift{Value: 1000})
+
+	for i := 0; i < 100; i++ {
+		want := i
+		if i >= 10 && i < 20 {
+			want += 1000
+		}
+
+		v, ok := lazyHandle.Get(updated, i)
+		require.True(t, ok)
+		assert.Equal(t, want, v, "key %d", i)
+
+		v, _ = lazyHandle.Get(root, i)
+		assert.Equal(t, i, v, "original should be unchanged")
+	}
+
+	t.Run("Iter", func(t *testing.T) {
+		var i int
+		for it := lazyHandle.Iter(updated); it.Valid(); it.Next() {
+			want := i
+			if i >= 10 && i < 20 {
+				want += 1000
+			}
+
+			assert.Equal(t, i, it.Key)
+			assert.Equal(t, want, it.Value)
+			i++
+		}
+		assert.Equal(t, 100, i)
+	})
+
+	t.Run("Children", func(t *testing.T) {
+		// the exported accessors must agree with Get
+		var walk func(n *treap.LazyNode) int
+		walk = func(n *treap.LazyNode) int {
+			if n == nil {
+				return 0
+			}
+
+			v, _ := lazyHandle.Get(updated, n.Key)
+			assert.Equal(t, v, n.Value, "key %d", n.Key)
+			return 1 + walk(n.Left()) + walk(n.Right())
+		}
+
+		assert.Equal(t, 100, walk(updated))
+	})
+
+	t.Run("Weight", func(t *testing.T) {
+		// moving a range ahead of every other weight must restore the heap property
+		n := lazyHandle.UpdateRange(updated, 50, 60, treap.Closed, shift{Weight: -10000})
+
+		var popped []int
+		for i := 0; i < 11; i++ {
+			var v interface{}
+			v, n = lazyHandle.Pop(n)
+			popped = append(popped, v.(int))
+		}
+
+		assert.ElementsMatch(t, []int{50, 51, 52, 53, 54, 55, 56, 57, 58, 59, 60}, popped)
+		assertLazyHeap(t, n)
+	})
+
+	t.Run("Compose", func(t *testing.T) {
+		n := lazyHandle.UpdateRange(updated, 0, 50, treap.ClosedOpen, shift{Value: 1})
+		n = lazyHandle.UpdateRange(n, 15, 30, treap.ClosedOpen, shift{Value: 10})
+
+		for _, tt := range []struct{ key, want int }{
+			{5, 6},
+			{12, 1013},
+			{17, 1028},
+			{25, 36},
+			{40, 41},
+			{70, 70},
+		} {
+			v, _ := lazyHandle.Get(n, tt.key)
+			assert.Equal(t, tt.want, v, "key %d", tt.key)
+		}
+	})
+}
+
+func TestUpdateRange_ReadsDoNotAllocate(t *testing.T) {
+	var root *treap.LazyNode
+	for i := 0; i < 100; i++ {
+		// keep weights small, so that boxing the updated weight doesn't allocate
+		root, _ = lazyHandle.Insert(root, i, i, rand.Intn(200)+1)
+	}
+	root = lazyHandle.UpdateRange(root, 10, 20, treap.ClosedOpen, shift{Value: 1})
+
+	allocs := testing.AllocsPerRun(100, func() {
+		lazyHandle.GetWeight(root, 15)
+	})
+	assert.Zero(t, allocs, "reads should not copy nodes")
+}
+
+func TestUpdateRange_Fuzz(t *testing.T) {
+	t.Parallel()
+
+	var (
+		rng   = rand.New(rand.NewSource(1))
+		root  *treap.LazyNode
+		model = make(map[int][2]int) // key -> (value, weight)
+	)
+
+	for i := 0; i < 2000; i++ {
+		k := rng.Intn(200)
+
+		switch rng.Intn(4) {
+		case 0:
+			v, w := rng.Int(), rng.Intn(1000)
+			root, _ = lazyHandle.Upsert(root, k, v, w)
+			model[k] = [2]int{v, w}
+
+		case 1:
+			root = lazyHandle.Delete(root, k)
+			delete(model, k)
+
+		case 2:
+			hi := k + rng.Intn(50)
+			s := shift{Value: rng.Intn(10), Weight: rng.Intn(10) - 5}
+			root = lazyHandle.UpdateRange(root, k, hi, treap.ClosedOpen, s)
+
+			for key, e := range model {
+				if key >= k && key < hi {
+					model[key] = [2]int{e[0] + s.Value, e[1] + s.Weight}
+				}
+			}
+
+		case 3:
+			if e, ok := model[k]; ok {
+				w := rng.Intn(1000)
+				root, _ = lazyHandle.SetWeight(root, k, w)
+				model[k] = [2]int{e[0], w}
+			}
+		}
+	}
+
+	var n int
+	for it := lazyHandle.Iter(root); it.Valid(); it.Next() {
+		e, ok := model[it.Key.(int)]
+		require.True(t, ok, "unexpected key %d", it.Key)
+		assert.Equal(t, e[0], it.Value)
+		assert.Equal(t, e[1], it.Weight)
+		n++
+	}
+
+	assert.Equal(t, len(model), n)
+	assertLazyHeap(t, root)
+}
+
+func TestUpdateAll(t *testing.T) {
+	t.Parallel()
+
+	var root *treap.LazyNode
+	for i, w := range rand.Perm(100) {
+		root, _ = lazyHandle.Insert(root, i, nil, w+1)
+	}
+
+	aged := lazyHandle.UpdateAll(root, treap.Offset{Delta: -1000})
+	assert.Equal(t, root.Weight.(int)-1000, aged.Weight)
+
+	w, _ := lazyHandle.GetWeight(aged, 42)
+	want, _ := lazyHandle.GetWeight(root, 42)
+	assert.Equal(t, want.(int)-1000, w)
+
+	// a new item that is heavier than the aged ones must sink to the bottom
+	aged, _ = lazyHandle.Insert(aged, -1, nil, 0)
+	assertLazyHeap(t, aged)
+
+	var (
+		popped []interface{}
+		n      = aged
+	)
+	for n != nil {
+		var v interface{}
+		v, n = lazyHandle.Pop(n)
+		popped = append(popped, v)
+	}
+	assert.Len(t, popped, 101)
+}
+
+// assertLazyHeap pops every item off the treap, and checks that they are popped in
+// order of weight.
+func assertLazyHeap(t *testing.T, n *treap.LazyNode) {
+	t.Helper()
+
+	for prev := n; n != nil; _, n = lazyHandle.Pop(n) {
+		require.LessOrEqual(t, prev.Weight.(int), n.Weight.(int), "heap property violated")
+		prev = n
+	}
+}
+
+func TestOffset(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		weight, delta, want interface{}
+	}{
+		{nil, 1, nil},
+		{1, 2, 3},
+		{uint8(1), uint8(2), uint8(3)},
+		{1.5, 1., 2.5},
+		{time.Second, time.Second, 2 * time.Second},
+		{t0, time.Hour, t0.Add(time.Hour)},
+	} {
+		_, w := treap.Offset{Delta: tt.delta}.Apply("v", tt.weight)
+		assert.Equal(t, tt.want, w, "%T", tt.weight)
+	}
+
+	assert.Panics(t, func() { treap.Offset{Delta: 1}.Apply(nil, "a") })
+
+	t.Run("Overflow", func(t *testing.T) {
+		for _, tt := range []struct {
+			weight, delta interface{}
+		}{
+			{math.MaxInt, 1},
+			{math.MinInt, -1},
+			{int8(math.MaxInt8), int8(1)},
+			{int64(math.MinInt64), int64(-1)},
+			{uint(math.MaxUint), uint(1)},
+			{uint8(math.MaxUint8), uint8(1)},
+			{uint16(65000), uint16(1000)},
+			{uint64(math.MaxUint64), uint64(1)},
+			{time.Duration(math.MaxInt64), time.Nanosecond},
+		} {
+			assert.Panics(t, func() { treap.Offset{Delta: tt.delta}.Apply(nil, tt.weight) },
+				"%T", tt.weight)
+		}
+
+		_, w := treap.Offset{Delta: uint8(1)}.Apply(nil, uint8(math.MaxUint8-1))
+		assert.Equal(t, uint8(math.MaxUint8), w)
+
+		_, w = treap.Offset{Delta: -1}.Apply(nil, math.MinInt+1)
+		assert.Equal(t, math.MinInt, w)
+	})
+
+	u := treap.Offset{Delta: 1}.Compose(treap.Offset{Delta: 2})
+	assert.Equal(t, treap.Offset{Delta: 3}, u)
+}
+
+func TestScale(t *testing.T) {
+	t.Parallel()
+
+	_, w := treap.Scale{Factor: 0.5}.Apply(nil, 10)
+	assert.Equal(t, 5, w)
+
+	_, w = treap.Scale{Factor: 2}.Apply(nil, time.Second)
+	assert.Equal(t, 2*time.Second, w)
+
+	for _, f := range []float64{0, -1, math.NaN()} {
+		assert.Panics(t, func() { treap.Scale{Factor: f}.Apply(nil, 10) }, "factor %v", f)
+	}
+
+	assert.Panics(t, func() { treap.Scale{Factor: 2}.Apply(nil, math.MaxInt) })
+	assert.Panics(t, func() { treap.Scale{Factor: 2}.Apply(nil, int8(100)) })
+	assert.Panics(t, func() { treap.Scale{Factor: 2}.Apply(nil, uint8(200)) })
+	assert.Panics(t, func() { treap.Scale{Factor: 2}.Apply(nil, uint64(math.MaxUint64/2+1)) })
+
+	_, w = treap.Scale{Factor: 0.5}.Apply(nil, uint64(math.MaxUint64))
+	assert.Equal(t, uint64(1<<63), w)
+
+	u := treap.Scale{Factor: 2}.Compose(treap.Scale{Factor: 3})
+	assert.Equal(t, treap.Scale{Factor: 6}, u)
+
+	// mixed updates are applied in order
+	u = treap.Scale{Factor: 2}.Compose(treap.Offset{Delta: 1.})
+	_, w = u.Apply(nil, 10.)
+	assert.Equal(t, 21., w)
+
+	u = u.Compose(treap.Scale{Factor: 10})
+	_, w = u.Apply(nil, 10.)
+	assert.Equal(t, 210., w)
+}
@@ -0,0 +1,271 @@
+package treap
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// Version is a change committed to an Atomic treap.
+type Version struct {
+	Old, New *Node
+}
+
+// WatchPolicy determines how a Watcher behaves when its subscriber falls behind.
+type WatchPolicy uint8
+
+const (
+	// WatchDrop discards versions that do not fit in the channel buffer.
+	WatchDrop WatchPolicy = iota
+
+	// WatchCoalesce merges undelivered versions, such that the subscriber always
+	// receives a single version spanning from the oldest undelivered Old root to the
+	// latest New root.
+	WatchCoalesce
+
+	// WatchBlock blocks writers until the subscriber has received the version.
+	WatchBlock
+)
+
+// WatchOptions configure a Watcher.
+type WatchOptions struct {
+	// Policy for slow subscribers.  Defaults to WatchDrop.
+	Policy WatchPolicy
+
+	// Buffer is the capacity of the channel.  It is ignored by WatchCoalesce, whose
+	// capacity is always 1.
+	Buffer int
+
+	// If either of Lo or Hi is non-nil, only versions that change an entry whose key
+	// lies in the range [Lo, Hi) are delivered.  A nil bound is unbounded.
+	//
+	// Changes are detected by comparing the identity of the entries' keys, values and
+	// weights, so re-assigning an equal, but separately-allocated, value to a key counts
+	// as a change.
+	Lo, Hi interface{}
+}
+
+// Watcher delivers the versions committed to an Atomic treap.
+type Watcher struct {
+	// C receives each committed version, in commit order.  It is closed by Close.
+	C <-chan Version
+
+	a    *Atomic
+	opt  WatchOptions
+	ch   chan Version
+	once sync.Once
+	done chan struct{}
+}
+
+// Watch subscribes to changes committed to the treap after Watch returns.  The
+// returned Watcher MUST be closed when it is no longer needed.
+//
+// While at least one Watcher is active, commits are serialized in order to preserve
+// their ordering.  Reads are unaffected, and remain wait-free.
+func (a *Atomic) Watch(opt WatchOptions) *Watcher {
+	buf := opt.Buffer
+	if opt.Policy == WatchCoalesce {
+		buf = 1
+	}
+
+	ch := make(chan Version, buf)
+	w := &Watcher{
+		C:    ch,
+		a:    a,
+		opt:  opt,
+		ch:   ch,
+		done: make(chan struct{}),
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.watchers = append(a.watchers, w)
+	return w
+}
+
+// Close the watcher and its channel.  It is safe to call Close while a writer is
+// blocked on the watcher.
+func (w *Watcher) Close() {
+	w.once.Do(func() {
+		close(w.done) // unblock writers
+
+		w.a.mu.Lock()
+		defer w.a.mu.Unlock()
+
+		for i, other := range w.a.watchers {
+			if other == w {
+				w.a.watchers = append(w.a.watchers[:i:i], w.a.watchers[i+1:]...)
+				break
+			}
+		}
+
+		close(w.ch)
+	})
+}
+
+// publish v to all watchers.  The caller MUST hold a.mu exclusively.
+func (a *Atomic) publish(v Version) {
+	for _, w := range a.watchers {
+		if w.matches(a.h, v) {
+			w.send(v)
+		}
+	}
+}
+
+func (w *Watcher) send(v Version) {
+	switch w.opt.Policy {
+	case WatchBlock:
+		select {
+		case w.ch <- v:
+		case <-w.done:
+		}
+
+	case WatchCoalesce:
+		select {
+		case w.ch <- v:
+		default:
+			// Merge with the pending version, if the subscriber has not consumed it
+			// in the meantime.  The buffer is then guaranteed to have space, since
+			// the caller holds the only reference that sends on w.ch.
+			select {
+			case prev := <-w.ch:
+				v.Old = prev.Old
+			default:
+			}
+
+			w.ch <- v
+		}
+
+	default:
+		select {
+		case w.ch <- v:
+		default:
+		}
+	}
+}
+
+// matches reports whether v changes an entry in the watcher's key range.
+//
+// The old and new treaps are walked in key order, side by side.  Subtrees that are
+// shared by both treaps are skipped, so the cost is proportional to the size of the
+// change, rather than to the size of the range.
+func (w *Watcher) matches(h Handle, v Version) bool {
+	if w.opt.Lo == nil && w.opt.Hi == nil {
+		return true
+	}
+
+	if v.Old == v.New {
+		return false
+	}
+
+	old, new := newDiffStack(v.Old), newDiffStack(v.New)
+	for {
+		switch {
+		case len(old) == 0 && len(new) == 0:
+			return false
+
+		case len(old) == 0:
+			if new.top().entry {
+				return true // inserted
+			}
+			new = w.expand(h, new)
+			continue
+
+		case len(new) == 0:
+			if old.top().entry {
+				return true // deleted
+			}
+			old = w.expand(h, old)
+			continue
+		}
+
+		a, b := old.top(), new.top()
+		switch {
+		case a.entry && b.entry:
+			if !sameEntry(a.n, b.n) {
+				return true
+			}
+			old, new = old[:len(old)-1], new[:len(new)-1]
+
+		case a.n == b.n && !a.entry && !b.entry:
+			// shared subtree; its entries are identical in both treaps
+			old, new = old[:len(old)-1], new[:len(new)-1]
+
+		case a.entry:
+			new = w.expand(h, new)
+
+		case b.entry:
+			old = w.expand(h, old)
+
+		default:
+			// Expand the taller subtree first, since it may contain the other one.
+			// Ties are expanded together.
+			c := h.CompareWeights(a.n.Weight, b.n.Weight)
+			if c <= 0 {
+				old = w.expand(h, old)
+			}
+			if c >= 0 {
+				new = w.expand(h, new)
+			}
+		}
+	}
+}
+
+// diffStack holds the parts of a treap that remain to be walked, in reverse key order.
+type diffStack []diffItem
+
+// diffItem is a single entry, or an entire subtree.
+type diffItem struct {
+	n     *Node
+	entry bool
+}
+
+func newDiffStack(n *Node) diffStack {
+	if n == nil {
+		return nil
+	}
+
+	return diffStack{{n: n}}
+}
+
+func (s diffStack) top() diffItem {
+	return s[len(s)-1]
+}
+
+// expand replaces the subtree at the top of the stack with its parts, omitting those
+// that lie outside of the watcher's key range.
+func (w *Watcher) expand(h Handle, s diffStack) diffStack {
+	n := s.top().n
+	s = s[:len(s)-1]
+
+	belowHi := w.opt.Hi == nil || h.CompareKeys(n.Key, w.opt.Hi) < 0
+	aboveLo := w.opt.Lo == nil || h.CompareKeys(n.Key, w.opt.Lo) >= 0
+
+	if n.Right != nil && belowHi {
+		s = append(s, diffItem{n: n.Right})
+	}
+
+	if belowHi && aboveLo {
+		s = append(s, diffItem{n: n, entry: true})
+	}
+
+	if n.Left != nil && (w.opt.Lo == nil || h.CompareKeys(n.Key, w.opt.Lo) > 0) {
+		s = append(s, diffItem{n: n.Left})
+	}
+
+	return s
+}
+
+// sameEntry reports whether two nodes hold identical entries.  Nodes that were copied
+// while updating a treap hold identical entries, even though they are distinct.
+func sameEntry(a, b *Node) bool {
+	return a == b || (sameInterface(a.Key, b.Key) &&
+		sameInterface(a.Value, b.Value) &&
+		sameInterface(a.Weight, b.Weight))
+}
+
+// sameInterface reports whether a and b hold the same dynamic type and data pointer.
+// Unlike ==, it never panics.
+func sameInterface(a, b interface{}) bool {
+	return *(*[2]unsafe.Pointer)(unsafe.Pointer(&a)) == *(*[2]unsafe.Pointer)(unsafe.Pointer(&b))
+}
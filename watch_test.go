@@ -0,0 +1,222 @@
+package treap_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	upsert := func(a *treap.Atomic, key, val interface{}) *treap.Node {
+		return a.Update(func(n *treap.Node) *treap.Node {
+			n, _ = handle.Upsert(n, key, val, key)
+			return n
+		})
+	}
+
+	t.Run("Order", func(t *testing.T) {
+		a := treap.NewAtomic(handle)
+		w := a.Watch(treap.WatchOptions{Buffer: 10})
+		defer w.Close()
+
+		var roots []*treap.Node
+		for i := 0; i < 5; i++ {
+			roots = append(roots, upsert(a, i, i))
+		}
+
+		var prev *treap.Node
+		for i := 0; i < 5; i++ {
+			v := <-w.C
+			assert.Equal(t, prev, v.Old)
+			assert.Equal(t, roots[i], v.New)
+			prev = v.New
+		}
+	})
+
+	t.Run("Drop", func(t *testing.T) {
+		a := treap.NewAtomic(handle)
+		w := a.Watch(treap.WatchOptions{Policy: treap.WatchDrop, Buffer: 1})
+		defer w.Close()
+
+		first := upsert(a, 1, 1)
+		upsert(a, 2, 2)
+
+		v := <-w.C
+		assert.Equal(t, first, v.New)
+		assertNoVersion(t, w)
+	})
+
+	t.Run("Coalesce", func(t *testing.T) {
+		a := treap.NewAtomic(handle)
+		w := a.Watch(treap.WatchOptions{Policy: treap.WatchCoalesce})
+		defer w.Close()
+
+		upsert(a, 1, 1)
+		upsert(a, 2, 2)
+		last := upsert(a, 3, 3)
+
+		v := <-w.C
+		assert.Nil(t, v.Old, "coalesced version should span from the oldest root")
+		assert.Equal(t, last, v.New, "coalesced version should span to the latest root")
+		assertNoVersion(t, w)
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		a := treap.NewAtomic(handle)
+		w := a.Watch(treap.WatchOptions{Policy: treap.WatchBlock})
+		defer w.Close()
+
+		done := make(chan *treap.Node)
+		go func() { done <- upsert(a, 1, 1) }()
+
+		select {
+		case <-done:
+			t.Fatal("writer should block until the version is received")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		v := <-w.C
+		assert.Equal(t, <-done, v.New)
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		a := treap.NewAtomic(handle)
+		for i := 0; i < 30; i++ {
+			upsert(a, i, i)
+		}
+
+		w := a.Watch(treap.WatchOptions{Buffer: 10, Lo: 10, Hi: 20})
+		defer w.Close()
+
+		upsert(a, 5, "changed")
+		upsert(a, 20, "changed")
+		a.Update(func(n *treap.Node) *treap.Node { return handle.Delete(n, 25) })
+		assertNoVersion(t, w)
+
+		want := upsert(a, 15, "changed")
+		v := <-w.C
+		assert.Equal(t, want, v.New)
+
+		want = a.Update(func(n *treap.Node) *treap.Node { return handle.Delete(n, 10) })
+		v = <-w.C
+		assert.Equal(t, want, v.New)
+
+		want = upsert(a, 19, "changed")
+		v = <-w.C
+		assert.Equal(t, want, v.New)
+	})
+
+	t.Run("RangeFuzz", func(t *testing.T) {
+		var (
+			rng     = rand.New(rand.NewSource(1))
+			a       = treap.NewAtomic(handle)
+			present = make(map[int]bool)
+		)
+
+		for i := 0; i < 100; i += 2 {
+			a.Update(func(n *treap.Node) *treap.Node {
+				n, _ = handle.Upsert(n, i, rng.Int(), rng.Intn(1000))
+				return n
+			})
+			present[i] = true
+		}
+
+		w := a.Watch(treap.WatchOptions{Buffer: 1, Lo: 25, Hi: 75})
+		defer w.Close()
+
+		for i := 0; i < 1000; i++ {
+			k := rng.Intn(100)
+
+			changed := true
+			if rng.Intn(3) == 0 {
+				changed = present[k]
+				delete(present, k)
+				a.Update(func(n *treap.Node) *treap.Node { return handle.Delete(n, k) })
+			} else {
+				present[k] = true
+				a.Update(func(n *treap.Node) *treap.Node {
+					n, _ = handle.Upsert(n, k, rng.Int(), rng.Intn(1000))
+					return n
+				})
+			}
+
+			if changed && k >= 25 && k < 75 {
+				select {
+				case <-w.C:
+				default:
+					t.Fatalf("change to key %d was not delivered", k)
+				}
+			} else {
+				assertNoVersion(t, w)
+			}
+		}
+	})
+
+	t.Run("RangeCost", func(t *testing.T) {
+		var calls int
+		h := treap.Handle{
+			CompareWeights: treap.IntComparator,
+			CompareKeys: func(a, b interface{}) int {
+				calls++
+				return treap.IntComparator(a, b)
+			},
+		}
+
+		a := treap.NewAtomic(h)
+		a.Update(func(n *treap.Node) *treap.Node {
+			for i := 0; i < 10000; i++ {
+				n, _ = h.Upsert(n, i, i, rand.Int())
+			}
+			return n
+		})
+
+		w := a.Watch(treap.WatchOptions{Buffer: 1, Lo: 0, Hi: 10000})
+		defer w.Close()
+
+		calls = 0
+		a.Update(func(n *treap.Node) *treap.Node {
+			n, _ = h.Upsert(n, 5000, "changed", rand.Int())
+			return n
+		})
+		<-w.C
+
+		assert.Less(t, calls, 500, "watcher should not walk unchanged subtrees")
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		a := treap.NewAtomic(handle)
+		w := a.Watch(treap.WatchOptions{Policy: treap.WatchBlock})
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			upsert(a, 1, 1)
+		}()
+
+		time.Sleep(10 * time.Millisecond) // let the writer block
+		w.Close()
+		w.Close() // idempotent
+
+		<-done // writer must be released
+		_, ok := <-w.C
+		assert.False(t, ok, "channel should be closed")
+
+		upsert(a, 2, 2) // must not block or panic
+	})
+}
+
+func assertNoVersion(t *testing.T, w *treap.Watcher) {
+	t.Helper()
+
+	select {
+	case v, ok := <-w.C:
+		require.False(t, ok, "unexpected version %v", v)
+	default:
+	}
+}
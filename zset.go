@@ -0,0 +1,359 @@
+package treap
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// SortedSet is a persistent, Redis-style sorted set of string members, each of which
+// is associated with a float64 score.  Members are ordered by score, with ties broken
+// by comparing members lexicographically.
+//
+// A treap's weights are heap-ordered, so they cannot be queried by range.  SortedSet
+// therefore pairs a treap keyed by member with a score index keyed by (score, member),
+// and keeps them in sync.  The score index is a treap whose nodes record the size of
+// their subtrees, so that members can be located by rank in O(log n).  Node priorities
+// are derived from a hash of the member, so the shape of both treaps is independent of
+// the order of insertion.
+//
+// SortedSet is an immutable value; its methods return a new SortedSet and leave the
+// receiver unchanged.  The zero value is an empty set, and is ready to use.  Scores
+// MUST NOT be NaN; ZAdd and ZIncrBy panic if they are.
+type SortedSet struct {
+	members *Node     // member -> score
+	scores  *zsetNode // score index
+}
+
+// ZMember is a member of a SortedSet, along with its score.
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+var zsetMembers = Handle{
+	CompareKeys:    StringComparator,
+	CompareWeights: UInt64Comparator,
+}
+
+// Len returns the number of members in the set.
+func (z SortedSet) Len() int {
+	return z.scores.len()
+}
+
+// ZAdd sets the score of a member, adding it to the set if it is not already present.
+// Returns true if the member was added.  Panics if score is NaN, since NaN is
+// unordered.
+//
+// O(log n)
+func (z SortedSet) ZAdd(member string, score float64) (SortedSet, bool) {
+	if math.IsNaN(score) {
+		panic("treap: sorted set score is NaN")
+	}
+
+	prio := zsetPriority(member)
+
+	old, found := zsetMembers.Get(z.members, member)
+	if found {
+		if old.(float64) == score {
+			return z, false
+		}
+
+		z.scores = z.scores.delete(ZMember{member, old.(float64)})
+	}
+
+	z.members, _ = zsetMembers.Upsert(z.members, member, score, prio)
+	z.scores = z.scores.insert(ZMember{member, score}, prio)
+	return z, !found
+}
+
+// ZIncrBy increments the score of a member by incr, and returns the new score.  If the
+// member is absent, it is added with a score of incr.
+//
+// O(log n)
+func (z SortedSet) ZIncrBy(member string, incr float64) (SortedSet, float64) {
+	score, _ := z.ZScore(member)
+	score += incr
+
+	z, _ = z.ZAdd(member, score)
+	return z, score
+}
+
+// ZRem removes a member.  Returns false if the member was not present.
+//
+// O(log n)
+func (z SortedSet) ZRem(member string) (SortedSet, bool) {
+	score, ok := z.ZScore(member)
+	if !ok {
+		return z, false
+	}
+
+	z.members = zsetMembers.Delete(z.members, member)
+	z.scores = z.scores.delete(ZMember{member, score})
+	return z, true
+}
+
+// ZScore returns the score of a member.  Returns false if the member is not present.
+//
+// O(log n)
+func (z SortedSet) ZScore(member string) (float64, bool) {
+	score, ok := zsetMembers.Get(z.members, member)
+	if !ok {
+		return 0, false
+	}
+
+	return score.(float64), true
+}
+
+// ZRank returns the zero-based rank of a member, ordered from lowest to highest score.
+// Returns false if the member is not present.
+//
+// O(log n)
+func (z SortedSet) ZRank(member string) (int, bool) {
+	score, ok := z.ZScore(member)
+	if !ok {
+		return 0, false
+	}
+
+	return z.scores.rank(ZMember{member, score}), true
+}
+
+// ZRangeByScore returns the members whose scores lie in the closed interval
+// [min, max], ordered by score.
+//
+// O(log n + m), where m is the number of members returned.
+func (z SortedSet) ZRangeByScore(min, max float64) []ZMember {
+	var ms []ZMember
+
+	// The empty string is the smallest member, so this seeks to the first member
+	// whose score is >= min.
+	it := z.scores.seek(ZMember{Score: min})
+	for m, ok := it.next(); ok && m.Score <= max; m, ok = it.next() {
+		ms = append(ms, m)
+	}
+
+	return ms
+}
+
+// ZRangeByRank returns the members whose ranks lie in the closed interval
+// [start, stop], ordered by score.  As in Redis, negative indices count from the
+// highest score, so that -1 is the last member.  Out-of-range indices are clamped.
+//
+// O(log n + m), where m is the number of members returned.
+func (z SortedSet) ZRangeByRank(start, stop int) []ZMember {
+	n := z.Len()
+
+	if start < 0 {
+		start += n
+	}
+
+	if stop < 0 {
+		stop += n
+	}
+
+	if start < 0 {
+		start = 0
+	}
+
+	if stop >= n {
+		stop = n - 1
+	}
+
+	if start > stop {
+		return nil
+	}
+
+	ms := make([]ZMember, 0, stop-start+1)
+
+	it := z.scores.seekRank(start)
+	for len(ms) < cap(ms) {
+		m, _ := it.next()
+		ms = append(ms, m)
+	}
+
+	return ms
+}
+
+// zsetNode is a node of a SortedSet's score index.  It is keyed by ZMember, and
+// records the size of its subtree.  Nodes are immutable;  the methods below copy the
+// path to any node they change, and keep sizes up to date along the way.
+type zsetNode struct {
+	ZMember
+	prio        uint64 // min-heap ordered
+	size        int
+	left, right *zsetNode
+}
+
+// len returns the size of the subtree rooted at n.
+func (n *zsetNode) len() int {
+	if n == nil {
+		return 0
+	}
+
+	return n.size
+}
+
+// with returns a copy of n with the specified children.
+func (n *zsetNode) with(left, right *zsetNode) *zsetNode {
+	c := *n
+	c.left, c.right = left, right
+	c.size = 1 + left.len() + right.len()
+	return &c
+}
+
+// insert a member that is not already present.
+func (n *zsetNode) insert(m ZMember, prio uint64) *zsetNode {
+	if n == nil || prio < n.prio {
+		left, right := n.split(m)
+		return &zsetNode{
+			ZMember: m,
+			prio:    prio,
+			size:    1 + left.len() + right.len(),
+			left:    left,
+			right:   right,
+		}
+	}
+
+	if compareZMembers(m, n.ZMember) < 0 {
+		return n.with(n.left.insert(m, prio), n.right)
+	}
+
+	return n.with(n.left, n.right.insert(m, prio))
+}
+
+// split the subtree into members that sort before m, and members that sort after m.
+func (n *zsetNode) split(m ZMember) (*zsetNode, *zsetNode) {
+	if n == nil {
+		return nil, nil
+	}
+
+	if compareZMembers(m, n.ZMember) < 0 {
+		left, right := n.left.split(m)
+		return left, n.with(right, n.right)
+	}
+
+	left, right := n.right.split(m)
+	return n.with(n.left, left), right
+}
+
+// delete a member.  It is a nop if the member is not present.
+func (n *zsetNode) delete(m ZMember) *zsetNode {
+	if n == nil {
+		return nil
+	}
+
+	switch c := compareZMembers(m, n.ZMember); {
+	case c < 0:
+		return n.with(n.left.delete(m), n.right)
+	case c > 0:
+		return n.with(n.left, n.right.delete(m))
+	default:
+		return n.left.merge(n.right)
+	}
+}
+
+// merge two subtrees, where every member of n sorts before every member of other.
+func (n *zsetNode) merge(other *zsetNode) *zsetNode {
+	switch {
+	case n == nil:
+		return other
+	case other == nil:
+		return n
+	case n.prio <= other.prio:
+		return n.with(n.left, n.right.merge(other))
+	default:
+		return other.with(n.merge(other.left), other.right)
+	}
+}
+
+// rank returns the number of members that sort before m, which MUST be present.
+func (n *zsetNode) rank(m ZMember) (rank int) {
+	for n != nil {
+		switch c := compareZMembers(m, n.ZMember); {
+		case c < 0:
+			n = n.left
+		case c > 0:
+			rank += n.left.len() + 1
+			n = n.right
+		default:
+			return rank + n.left.len()
+		}
+	}
+
+	return rank
+}
+
+// seek returns an iterator positioned at the first member that is >= m.
+func (n *zsetNode) seek(m ZMember) (it zsetIter) {
+	for n != nil {
+		if compareZMembers(n.ZMember, m) >= 0 {
+			it = append(it, n)
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+
+	return it
+}
+
+// seekRank returns an iterator positioned at the member with the specified rank.
+func (n *zsetNode) seekRank(rank int) (it zsetIter) {
+	for n != nil {
+		switch size := n.left.len(); {
+		case rank < size:
+			it = append(it, n)
+			n = n.left
+		case rank > size:
+			rank -= size + 1
+			n = n.right
+		default:
+			return append(it, n)
+		}
+	}
+
+	return it
+}
+
+// zsetIter walks a score index in order.  It is a stack of the nodes that remain to be
+// visited, along with their right subtrees, with the next node on top.
+type zsetIter []*zsetNode
+
+// next returns the next member.  Returns false if the iterator is exhausted.
+func (it *zsetIter) next() (ZMember, bool) {
+	s := *it
+	if len(s) == 0 {
+		return ZMember{}, false
+	}
+
+	n := s[len(s)-1]
+	s = s[:len(s)-1]
+	for c := n.right; c != nil; c = c.left {
+		s = append(s, c)
+	}
+
+	*it = s
+	return n.ZMember, true
+}
+
+func compareZMembers(a, b ZMember) int {
+	switch {
+	case a.Score < b.Score:
+		return -1
+	case a.Score > b.Score:
+		return 1
+	case a.Member < b.Member:
+		return -1
+	case a.Member > b.Member:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// zsetPriority derives a pseudo-random node priority from a member, so that the shape
+// of each treap depends only on its contents.
+func zsetPriority(member string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(member))
+	return h.Sum64()
+}
@@ -0,0 +1,176 @@
+package treap_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/lthibault/treap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortedSet(t *testing.T) {
+	t.Parallel()
+
+	var z treap.SortedSet
+
+	for _, m := range []treap.ZMember{
+		{"alice", 30},
+		{"bob", 10},
+		{"carol", 20},
+		{"dave", 20},
+		{"erin", 40},
+	} {
+		var added bool
+		z, added = z.ZAdd(m.Member, m.Score)
+		require.True(t, added)
+	}
+	require.Equal(t, 5, z.Len())
+
+	t.Run("ZAdd", func(t *testing.T) {
+		z2, added := z.ZAdd("bob", 50)
+		assert.False(t, added, "existing member should be updated")
+		assert.Equal(t, 5, z2.Len())
+
+		score, ok := z2.ZScore("bob")
+		assert.True(t, ok)
+		assert.Equal(t, 50., score)
+
+		score, _ = z.ZScore("bob")
+		assert.Equal(t, 10., score, "original should be unchanged")
+	})
+
+	t.Run("ZRem", func(t *testing.T) {
+		z2, ok := z.ZRem("carol")
+		assert.True(t, ok)
+		assert.Equal(t, 4, z2.Len())
+
+		_, ok = z2.ZRem("carol")
+		assert.False(t, ok)
+
+		_, ok = z2.ZScore("carol")
+		assert.False(t, ok)
+		assert.Equal(t, []treap.ZMember{{"dave", 20}}, z2.ZRangeByScore(15, 20))
+	})
+
+	t.Run("ZRank", func(t *testing.T) {
+		for i, m := range []string{"bob", "carol", "dave", "alice", "erin"} {
+			rank, ok := z.ZRank(m)
+			assert.True(t, ok)
+			assert.Equal(t, i, rank, m)
+		}
+
+		_, ok := z.ZRank("mallory")
+		assert.False(t, ok)
+	})
+
+	t.Run("ZRangeByScore", func(t *testing.T) {
+		assert.Equal(t, []treap.ZMember{
+			{"carol", 20},
+			{"dave", 20},
+			{"alice", 30},
+		}, z.ZRangeByScore(20, 30))
+
+		assert.Empty(t, z.ZRangeByScore(21, 29))
+		assert.Len(t, z.ZRangeByScore(-100, 100), 5)
+	})
+
+	t.Run("ZRangeByRank", func(t *testing.T) {
+		assert.Equal(t, []treap.ZMember{
+			{"carol", 20},
+			{"dave", 20},
+		}, z.ZRangeByRank(1, 2))
+
+		assert.Equal(t, []treap.ZMember{
+			{"alice", 30},
+			{"erin", 40},
+		}, z.ZRangeByRank(-2, -1))
+
+		assert.Len(t, z.ZRangeByRank(0, 100), 5)
+		assert.Empty(t, z.ZRangeByRank(3, 1))
+		assert.Empty(t, z.ZRangeByRank(10, 20))
+	})
+
+	t.Run("ZIncrBy", func(t *testing.T) {
+		z2, score := z.ZIncrBy("bob", 25)
+		assert.Equal(t, 35., score)
+
+		rank, _ := z2.ZRank("bob")
+		assert.Equal(t, 3, rank)
+
+		z2, score = z2.ZIncrBy("frank", 1.5)
+		assert.Equal(t, 1.5, score)
+		assert.Equal(t, 6, z2.Len())
+	})
+
+	t.Run("NaN", func(t *testing.T) {
+		assert.Panics(t, func() { z.ZAdd("bob", math.NaN()) })
+		assert.Panics(t, func() { z.ZAdd("mallory", math.NaN()) })
+		assert.Panics(t, func() {
+			z2, _ := z.ZAdd("inf", math.Inf(1))
+			z2.ZIncrBy("inf", math.Inf(-1))
+		})
+	})
+}
+
+func TestSortedSet_Rank(t *testing.T) {
+	t.Parallel()
+
+	var (
+		rng   = rand.New(rand.NewSource(1))
+		z     treap.SortedSet
+		model = make(map[string]float64)
+	)
+
+	for i := 0; i < 2000; i++ {
+		m := fmt.Sprintf("m%d", rng.Intn(500))
+
+		if rng.Intn(4) == 0 {
+			z, _ = z.ZRem(m)
+			delete(model, m)
+		} else {
+			score := float64(rng.Intn(100))
+			z, _ = z.ZAdd(m, score)
+			model[m] = score
+		}
+	}
+
+	want := make([]treap.ZMember, 0, len(model))
+	for m, score := range model {
+		want = append(want, treap.ZMember{Member: m, Score: score})
+	}
+	sort.Slice(want, func(i, j int) bool {
+		if want[i].Score != want[j].Score {
+			return want[i].Score < want[j].Score
+		}
+		return want[i].Member < want[j].Member
+	})
+
+	require.Equal(t, len(want), z.Len())
+	assert.Equal(t, want, z.ZRangeByRank(0, -1))
+
+	for i, m := range want {
+		rank, ok := z.ZRank(m.Member)
+		require.True(t, ok)
+		require.Equal(t, i, rank, m.Member)
+	}
+
+	for i := 0; i < 100; i++ {
+		start, stop := rng.Intn(len(want)), rng.Intn(len(want))
+		if start > stop {
+			start, stop = stop, start
+		}
+		assert.Equal(t, want[start:stop+1], z.ZRangeByRank(start, stop))
+	}
+
+	var inRange []treap.ZMember
+	for _, m := range want {
+		if m.Score >= 25 && m.Score <= 50 {
+			inRange = append(inRange, m)
+		}
+	}
+	assert.Equal(t, inRange, z.ZRangeByScore(25, 50))
+}